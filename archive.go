@@ -0,0 +1,17 @@
+package onnx
+
+import "github.com/joeychilson/onnx/internal/archive"
+
+// ListArchiveContents returns the full paths of all entries in a zip or tar.gz archive.
+// Use one of them with WithArchiveEntry to pick a specific library when the name is ambiguous.
+func ListArchiveContents(archivePath string) ([]string, error) {
+	return archive.List(archivePath)
+}
+
+// RegisterArchiveFormat registers an extractor for archives whose name ends with ext
+// (e.g. ".tar.zst"), for use with WithURLTemplate pointing at other formats. The
+// extractor writes the entry matching target to destPath. Registering ".zip" or ".tgz"
+// replaces the built-in handling. WithFullExtraction only supports the built-in formats.
+func RegisterArchiveFormat(ext string, extractor func(archivePath, destPath, target string) error) {
+	archive.Register(ext, extractor)
+}
@@ -0,0 +1,44 @@
+package onnx
+
+import (
+	"sync"
+	"time"
+
+	"github.com/joeychilson/onnx/internal/download"
+)
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*download.Breaker)
+)
+
+// WithCircuitBreaker makes runtime downloads fail fast with ErrCircuitOpen for cooldown
+// after threshold consecutive failures within window, so a fleet doesn't hammer a failing
+// mirror. The breaker is shared by every Runtime in the process with the same base URL,
+// so repeated New calls during an outage see it too; the first configuration wins.
+func WithCircuitBreaker(threshold int, window, cooldown time.Duration) Option {
+	return func(r *Runtime) {
+		r.breakerThreshold = threshold
+		r.breakerWindow = window
+		r.breakerCooldown = cooldown
+	}
+}
+
+// runtimeDownloadOptions returns the download options for runtime archives, which go
+// through the circuit breaker when one is configured
+func (r *Runtime) runtimeDownloadOptions() []download.Option {
+	opts := r.downloadOptions()
+	if r.breakerThreshold <= 0 {
+		return opts
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	breaker, ok := breakers[r.baseURL]
+	if !ok {
+		breaker = download.NewBreaker(r.breakerThreshold, r.breakerWindow, r.breakerCooldown)
+		breakers[r.baseURL] = breaker
+	}
+	return append(opts, download.WithBreaker(breaker))
+}
@@ -0,0 +1,42 @@
+package onnx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// buildInfoPrefix starts the build info string ONNX Runtime compiles into its library,
+// the same string returned by the C API's GetBuildInfoString
+var buildInfoPrefix = []byte("ORT Build Info: ")
+
+// BuildInfo returns the build info string of the loaded ONNX Runtime library, which
+// lists the git commit, build type and compiler flags, e.g. to confirm a CUDA or
+// AVX512 build is actually in use. The bindings don't expose the C API call, so the
+// string is read from the library file.
+func (r *Runtime) BuildInfo() (string, error) {
+	loadedMu.Lock()
+	libPath := loadedLibrary
+	loadedMu.Unlock()
+
+	if libPath == "" {
+		return "", errors.New("no runtime library loaded")
+	}
+
+	data, err := os.ReadFile(libPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read runtime library: %w", err)
+	}
+
+	start := bytes.Index(data, buildInfoPrefix)
+	if start < 0 {
+		return "", fmt.Errorf("no build info found in %s", libPath)
+	}
+
+	info := data[start:]
+	if end := bytes.IndexByte(info, 0); end >= 0 {
+		info = info[:end]
+	}
+	return string(info), nil
+}
@@ -0,0 +1,136 @@
+package onnx_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joeychilson/onnx"
+)
+
+// TestUnixSocketClient downloads the runtime and a model through a client whose
+// transport dials a Unix socket, as used to reach an egress broker
+func TestUnixSocketClient(t *testing.T) {
+	socketDir, err := os.MkdirTemp("", "onnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(socketDir)
+	socketPath := filepath.Join(socketDir, "proxy.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	var libraryName string
+	var mu sync.Mutex
+	var requests []string
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests = append(requests, req.URL.Path)
+		mu.Unlock()
+
+		switch {
+		case strings.HasSuffix(req.URL.Path, ".tgz"):
+			archive, err := runtimeArchive(strings.TrimSuffix(path.Base(req.URL.Path), ".tgz"), libraryName)
+			if err != nil {
+				t.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write(archive)
+		case req.URL.Path == "/models/identity.onnx":
+			w.Write(onnx.TestModel())
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+
+	ctx := context.Background()
+	cachePath := t.TempDir()
+	opts := []onnx.Option{
+		onnx.WithHTTPClient(client),
+		onnx.WithBaseURL("http://onnx.invalid/releases"),
+		onnx.WithCachePath(cachePath),
+		onnx.WithLazyInit(true),
+	}
+
+	runtime, err := onnx.New(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runtime.Close()
+
+	info := runtime.RuntimeInfo()
+	if info.Arch == "" || info.OS == "win" {
+		t.Skip("no tar.gz runtime build for this platform")
+	}
+	libraryName = info.LibraryName
+
+	libPath, err := runtime.EnsureRuntime(ctx)
+	if err != nil {
+		t.Fatalf("EnsureRuntime: %v", err)
+	}
+	if data, err := os.ReadFile(libPath); err != nil || string(data) != "library" {
+		t.Fatalf("unexpected library at %s: %q, %v", libPath, data, err)
+	}
+
+	modelPath, err := onnx.DownloadModel(ctx, "http://onnx.invalid/models/identity.onnx", opts...)
+	if err != nil {
+		t.Fatalf("DownloadModel: %v", err)
+	}
+	if data, err := os.ReadFile(modelPath); err != nil || !bytes.Equal(data, onnx.TestModel()) {
+		t.Fatalf("unexpected model at %s: %v", modelPath, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests over the socket, got %v", requests)
+	}
+}
+
+// runtimeArchive builds a tar.gz laid out like an official runtime release
+func runtimeArchive(dir, libraryName string) ([]byte, error) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	content := []byte("library")
+	header := &tar.Header{Name: dir + "/lib/" + libraryName, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
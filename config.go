@@ -0,0 +1,124 @@
+package onnx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// RuntimeConfig is the effective configuration of a Runtime, suitable for logging
+// or attaching to bug reports. Secrets such as access tokens are omitted.
+type RuntimeConfig struct {
+	Version            string           `json:"version"`
+	OS                 string           `json:"os"`
+	Arch               string           `json:"arch"`
+	GPU                bool             `json:"gpu"`
+	Translated         bool             `json:"translated"`
+	BaseURL            string           `json:"base_url"`
+	URL                string           `json:"url,omitempty"`
+	BuildTag           string           `json:"build_tag,omitempty"`
+	URLTemplate        string           `json:"url_template,omitempty"`
+	CachePath          string           `json:"cache_path"`
+	CacheKey           string           `json:"cache_key,omitempty"`
+	ModelCacheDir      string           `json:"model_cache_dir"`
+	CacheFileMode      string           `json:"cache_file_mode"`
+	TempDir            string           `json:"temp_dir,omitempty"`
+	LibraryPath        string           `json:"library_path"`
+	EmbeddedLibrary    string           `json:"embedded_library,omitempty"`
+	ArchiveEntry       string           `json:"archive_entry,omitempty"`
+	ChecksumManifest   string           `json:"checksum_manifest,omitempty"`
+	SignatureKey       string           `json:"signature_key,omitempty"`
+	FullExtraction     bool             `json:"full_extraction"`
+	ProviderLibraries  []string         `json:"provider_libraries,omitempty"`
+	NestedExtraction   bool             `json:"nested_extraction"`
+	StreamingExtract   bool             `json:"streaming_extract"`
+	FailFastInit       bool             `json:"fail_fast_init"`
+	LazyInit           bool             `json:"lazy_init"`
+	InsecureTLS        bool             `json:"insecure_tls"`
+	CustomHTTPClient   bool             `json:"custom_http_client"`
+	CustomTLSConfig    bool             `json:"custom_tls_config"`
+	TransportTuning    *TransportTuning `json:"transport_tuning,omitempty"`
+	Observer           bool             `json:"observer"`
+	DownloadBufferSize int              `json:"download_buffer_size,omitempty"`
+	MaxRedirects       *int             `json:"max_redirects,omitempty"`
+	Multipart          int              `json:"multipart,omitempty"`
+	CircuitBreaker     string           `json:"circuit_breaker,omitempty"`
+	Deterministic      bool             `json:"deterministic"`
+	Provider           string           `json:"provider,omitempty"`
+	CUDADevice         int              `json:"cuda_device,omitempty"`
+	CPUArenaDisabled   bool             `json:"cpu_arena_disabled"`
+	IntraOpThreads     int              `json:"intra_op_threads"`
+	InterOpThreads     int              `json:"inter_op_threads"`
+}
+
+// Config returns the effective configuration of the Runtime.
+// Thread counts of 0 mean ONNX Runtime picks the default.
+func (r *Runtime) Config() RuntimeConfig {
+	info := r.RuntimeInfo()
+
+	config := RuntimeConfig{
+		Version:            info.Version,
+		OS:                 info.OS,
+		Arch:               info.Arch,
+		GPU:                info.GPU,
+		Translated:         info.Translated,
+		BaseURL:            r.baseURL,
+		BuildTag:           r.buildTag,
+		URLTemplate:        r.urlTemplate,
+		CachePath:          r.cachePath,
+		CacheKey:           r.cacheNamespace(),
+		ModelCacheDir:      r.modelsDir(),
+		CacheFileMode:      fmt.Sprintf("%#o", r.cacheFileMode),
+		TempDir:            r.tempDir,
+		EmbeddedLibrary:    r.embeddedName,
+		ArchiveEntry:       r.archiveEntry,
+		ChecksumManifest:   r.manifestName,
+		SignatureKey:       hex.EncodeToString(r.signingKey),
+		FullExtraction:     r.fullExtraction,
+		ProviderLibraries:  r.providerLibraries,
+		NestedExtraction:   r.nestedExtraction,
+		StreamingExtract:   r.streamingExtract,
+		FailFastInit:       r.failFastInit,
+		LazyInit:           r.lazyInit,
+		InsecureTLS:        r.insecureTLS,
+		CustomHTTPClient:   r.client != nil,
+		CustomTLSConfig:    r.tlsConfig != nil,
+		TransportTuning:    r.transportTuning,
+		Observer:           r.observer != nil,
+		DownloadBufferSize: r.downloadBufferSize,
+		Multipart:          r.multipart,
+		Deterministic:      r.deterministic,
+		Provider:           r.provider,
+		IntraOpThreads:     r.intraOpThreads,
+	}
+
+	if r.breakerThreshold > 0 {
+		config.CircuitBreaker = fmt.Sprintf("%d failures in %s, cooldown %s", r.breakerThreshold, r.breakerWindow, r.breakerCooldown)
+	}
+
+	if r.maxRedirects >= 0 {
+		maxRedirects := r.maxRedirects
+		config.MaxRedirects = &maxRedirects
+	}
+
+	if r.provider == "cuda" {
+		config.CUDADevice = r.cudaDevice
+		config.CPUArenaDisabled = r.disableCPUArenaOnGPU
+	}
+
+	if r.deterministic {
+		config.IntraOpThreads = 1
+		config.InterOpThreads = 1
+	}
+
+	switch {
+	case r.libraryPath != "":
+		config.LibraryPath = r.libraryPath
+	case r.embeddedFS != nil:
+		config.LibraryPath = filepath.Join(r.cachePath, "embedded", path.Base(r.embeddedName))
+	case info.Arch != "":
+		config.URL, config.LibraryPath, _ = r.runtimePaths(info)
+	}
+	return config
+}
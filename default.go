@@ -0,0 +1,61 @@
+package onnx
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+var (
+	defaultMu      sync.Mutex
+	defaultRuntime *Runtime
+)
+
+// Init creates the process-wide default Runtime used by package-level helpers
+func Init(ctx context.Context, opts ...Option) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultRuntime != nil {
+		return errors.New("default runtime already initialized")
+	}
+
+	runtime, err := New(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defaultRuntime = runtime
+	return nil
+}
+
+// Default returns the default Runtime, or nil if Init has not been called
+func Default() *Runtime {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultRuntime
+}
+
+// Shutdown closes the default Runtime created by Init
+func Shutdown() error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultRuntime == nil {
+		return nil
+	}
+
+	err := defaultRuntime.Close()
+	defaultRuntime = nil
+	return err
+}
+
+// NewSessionOptions returns session options configured from the default Runtime
+func NewSessionOptions() (*ort.SessionOptions, error) {
+	runtime := Default()
+	if runtime == nil {
+		return nil, ErrNotInitialized
+	}
+	return runtime.NewSessionOptions()
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package onnx
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isDiskFull reports whether err is caused by a full disk
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
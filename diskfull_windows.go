@@ -0,0 +1,17 @@
+package onnx
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows error codes for a full disk, from winerror.h
+const (
+	errorHandleDiskFull syscall.Errno = 39
+	errorDiskFull       syscall.Errno = 112
+)
+
+// isDiskFull reports whether err is caused by a full disk
+func isDiskFull(err error) bool {
+	return errors.Is(err, errorDiskFull) || errors.Is(err, errorHandleDiskFull)
+}
@@ -0,0 +1,100 @@
+package onnx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithEmbeddedLibrary uses a runtime library embedded in the binary (e.g. via go:embed)
+// instead of downloading one. The library is written to the cache path on first use.
+func WithEmbeddedLibrary(fsys fs.FS, name string) Option {
+	return func(r *Runtime) {
+		r.embeddedFS = fsys
+		r.embeddedName = name
+	}
+}
+
+// materializeEmbedded writes the embedded library to the cache and returns its path. A
+// SHA256 of the embedded library is kept next to the copy, so a rebuilt library is written
+// again even when its name and size are unchanged.
+func (r *Runtime) materializeEmbedded() (string, error) {
+	sum, err := r.embeddedSHA256()
+	if err != nil {
+		return "", err
+	}
+
+	libDir := filepath.Join(r.cachePath, "embedded")
+	if err := r.mkdirCache(libDir); err != nil {
+		return "", err
+	}
+
+	libPath := filepath.Join(libDir, path.Base(r.embeddedName))
+	sumPath := libPath + ".sha256"
+	if cached, err := os.ReadFile(sumPath); err == nil && string(cached) == sum {
+		if _, err := os.Stat(libPath); err == nil {
+			r.observeCacheHit(libPath)
+			return libPath, nil
+		}
+	}
+
+	src, err := r.embeddedFS.Open(r.embeddedName)
+	if err != nil {
+		return "", fmt.Errorf("failed to open embedded library: %w", err)
+	}
+	defer src.Close()
+
+	if err := r.writeCacheFile(libPath, src); err != nil {
+		return "", fmt.Errorf("failed to write embedded library: %w", err)
+	}
+
+	// Without the checksum the library is just written again next time
+	if err := r.writeCacheFile(sumPath, strings.NewReader(sum)); err != nil {
+		slog.Warn("onnx: failed to record embedded library checksum", "path", sumPath, "error", err)
+	}
+	return libPath, nil
+}
+
+// writeCacheFile writes src to path through a temporary file of its own, so processes
+// writing the same file concurrently each rename a complete copy into place
+func (r *Runtime) writeCacheFile(path string, src io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := io.Copy(f, src); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), r.cacheFileMode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// embeddedSHA256 returns the hex SHA256 of the embedded library
+func (r *Runtime) embeddedSHA256() (string, error) {
+	src, err := r.embeddedFS.Open(r.embeddedName)
+	if err != nil {
+		return "", fmt.Errorf("failed to open embedded library: %w", err)
+	}
+	defer src.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, src); err != nil {
+		return "", fmt.Errorf("failed to read embedded library: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,44 @@
+package onnx
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by New before any options are applied, so explicit
+// options always take precedence
+const (
+	EnvProvider       = "ONNX_PROVIDER"
+	EnvCUDADevice     = "ONNX_CUDA_DEVICE"
+	EnvIntraOpThreads = "ONNX_INTRA_THREADS"
+)
+
+// applyEnv configures the Runtime from environment variables
+func (r *Runtime) applyEnv() error {
+	if v := os.Getenv(EnvProvider); v != "" {
+		provider := strings.ToLower(v)
+		if provider != "cpu" && provider != "cuda" {
+			return fmt.Errorf("invalid %s %q: must be cpu or cuda", EnvProvider, v)
+		}
+		WithExecutionProvider(provider)(r)
+	}
+
+	if v := os.Getenv(EnvCUDADevice); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 0 {
+			return fmt.Errorf("invalid %s %q: must be a non-negative integer", EnvCUDADevice, v)
+		}
+		r.cudaDevice = id
+	}
+
+	if v := os.Getenv(EnvIntraOpThreads); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q: must be a non-negative integer", EnvIntraOpThreads, v)
+		}
+		r.intraOpThreads = n
+	}
+	return nil
+}
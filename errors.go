@@ -0,0 +1,62 @@
+package onnx
+
+import (
+	"errors"
+
+	"github.com/joeychilson/onnx/internal/archive"
+	"github.com/joeychilson/onnx/internal/download"
+)
+
+var (
+	// ErrDownload is returned when the runtime archive or a model cannot be downloaded
+	ErrDownload = errors.New("failed to download")
+
+	// ErrShortDownload is returned when the downloaded archive is smaller than its Content-Length
+	ErrShortDownload = download.ErrShortDownload
+
+	// ErrUnexpectedContent is returned when a download URL serves an HTML page instead of a file
+	ErrUnexpectedContent = download.ErrUnexpectedContent
+
+	// ErrCircuitOpen is returned without contacting the server while WithCircuitBreaker is tripped
+	ErrCircuitOpen = download.ErrCircuitOpen
+
+	// ErrUnsupportedTransport is returned when WithTLSConfig or WithTransportTuning can't be
+	// applied to the transport of a WithHTTPClient client
+	ErrUnsupportedTransport = errors.New("unsupported transport")
+
+	// ErrChecksumMismatch is returned when a downloaded archive does not match its expected checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrSignatureMismatch is returned when a runtime archive has no valid signature by the configured key
+	ErrSignatureMismatch = errors.New("signature verification failed")
+
+	// ErrInsufficientDiskSpace is returned when the disk fills up while downloading or
+	// extracting the runtime. A downloaded archive is kept so a retry doesn't fetch it again.
+	ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+	// ErrExtract is returned when the runtime library cannot be extracted from the archive
+	ErrExtract = errors.New("failed to extract runtime")
+
+	// ErrArchiveEntryNotFound is returned alongside ErrExtract when the archive has no entry
+	// matching the library name, e.g. a WithBaseURL mirror laying out its archives differently
+	ErrArchiveEntryNotFound = archive.ErrNotFound
+
+	// ErrExtractWrite is returned alongside ErrExtract when a matched entry cannot be written to disk
+	ErrExtractWrite = archive.ErrWrite
+
+	// ErrInitEnv is returned when the ONNX Runtime environment cannot be initialized
+	ErrInitEnv = errors.New("failed to initialize environment")
+
+	// ErrRuntimeConflict is returned when a different runtime library is already loaded in the process.
+	// Only one ONNX Runtime library can be loaded at a time; compare versions in separate processes.
+	ErrRuntimeConflict = errors.New("different runtime already loaded")
+
+	// ErrDisallowedOp is returned when a model uses an operator rejected by ValidateModelOps or RejectModelOps
+	ErrDisallowedOp = errors.New("model uses disallowed operators")
+
+	// ErrNotInitialized is returned by package-level helpers when Init has not been called
+	ErrNotInitialized = errors.New("default runtime not initialized")
+
+	// ErrUnsupportedPlatform is returned when no runtime build exists for the current OS and architecture
+	ErrUnsupportedPlatform = errors.New("unsupported platform")
+)
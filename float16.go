@@ -0,0 +1,108 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// Float32ToFloat16 converts f to IEEE 754 half precision bits, rounding to nearest even.
+// Values too large for float16 become infinity and values too small become zero or
+// subnormals. NaNs stay NaN.
+func Float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+
+	if e <= 0 {
+		// Below 2^-25 even rounding can't reach the smallest subnormal
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	// A carry out of the mantissa correctly bumps the exponent, up to infinity
+	half := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// Float16ToFloat32 converts IEEE 754 half precision bits to a float32, which is exact
+func Float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Normalize the subnormal
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
+
+// NewFloat16Tensor creates a float16 tensor from float32 values, for models with
+// float16 inputs. The caller must call Destroy on the tensor when no longer needed.
+func NewFloat16Tensor(shape ort.Shape, data []float32) (*ort.CustomDataTensor, error) {
+	if int64(len(data)) != shape.FlattenedSize() {
+		return nil, fmt.Errorf("shape %v needs %d values, got %d", shape, shape.FlattenedSize(), len(data))
+	}
+
+	buf := make([]byte, 2*len(data))
+	for i, v := range data {
+		binary.NativeEndian.PutUint16(buf[2*i:], Float32ToFloat16(v))
+	}
+	return ort.NewCustomDataTensor(shape, buf, ort.TensorElementDataTypeFloat16)
+}
+
+// Float16TensorData returns the values of a float16 tensor, such as a model output,
+// converted to float32
+func Float16TensorData(tensor *ort.CustomDataTensor) ([]float32, error) {
+	if tensor.DataType() != ort.TensorElementDataTypeFloat16 {
+		return nil, fmt.Errorf("tensor is not float16")
+	}
+
+	buf := tensor.GetData()
+	data := make([]float32, len(buf)/2)
+	for i := range data {
+		data[i] = Float16ToFloat32(binary.NativeEndian.Uint16(buf[2*i:]))
+	}
+	return data, nil
+}
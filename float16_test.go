@@ -0,0 +1,56 @@
+package onnx_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/joeychilson/onnx"
+)
+
+// TestFloat16RoundTrip converts every float16 to float32 and back
+func TestFloat16RoundTrip(t *testing.T) {
+	for i := 0; i <= math.MaxUint16; i++ {
+		h := uint16(i)
+		f := onnx.Float16ToFloat32(h)
+
+		if math.IsNaN(float64(f)) {
+			if got := onnx.Float32ToFloat16(f); got&0x7c00 != 0x7c00 || got&0x3ff == 0 {
+				t.Fatalf("NaN %#04x became %#04x", h, got)
+			}
+			continue
+		}
+		if got := onnx.Float32ToFloat16(f); got != h {
+			t.Fatalf("%#04x (%g) became %#04x", h, f, got)
+		}
+	}
+}
+
+// TestFloat32ToFloat16Rounding checks values halfway between two float16s round to even
+func TestFloat32ToFloat16Rounding(t *testing.T) {
+	tests := []struct {
+		name string
+		f    float32
+		want uint16
+	}{
+		{"tie to even down", 1 + 1.0/2048, 0x3c00},
+		{"tie to even up", 1 + 3.0/2048, 0x3c02},
+		{"above tie", math.Nextafter32(1+1.0/2048, 2), 0x3c01},
+		{"negative tie", -(1 + 1.0/2048), 0xbc00},
+		{"subnormal tie to zero", 0x1p-25, 0x0000},
+		{"subnormal tie to even", 3 * 0x1p-25, 0x0002},
+		{"tie into normal", 0x1p-14 - 0x1p-25, 0x0400},
+		{"largest", 65504, 0x7bff},
+		{"tie to infinity", 65520, 0x7c00},
+		{"below tie to infinity", math.Nextafter32(65520, 0), 0x7bff},
+		{"underflow", 0x1p-26, 0x0000},
+		{"infinity", float32(math.Inf(-1)), 0xfc00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := onnx.Float32ToFloat16(tt.f); got != tt.want {
+				t.Fatalf("Float32ToFloat16(%g) = %#04x, want %#04x", tt.f, got, tt.want)
+			}
+		})
+	}
+}
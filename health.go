@@ -0,0 +1,77 @@
+package onnx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// HealthStatus is the JSON body served by HealthHandler
+type HealthStatus struct {
+	Status   string `json:"status"`
+	Version  string `json:"version,omitempty"`
+	Provider string `json:"provider"`
+	GPU      bool   `json:"gpu"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthHandler returns a readiness handler that responds 200 once this Runtime is
+// initialized and SelfTest has passed, and 503 otherwise, with a HealthStatus JSON body.
+// The first probe starts initialization (for WithLazyInit) and the self-test in the
+// background and answers "initializing" until they finish, so probes never block or
+// cancel a download. A failed check is retried on the next probe.
+func (r *Runtime) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		provider := r.provider
+		if provider == "" {
+			provider = "cpu"
+		}
+		status := HealthStatus{Status: "unavailable", Provider: provider, GPU: r.gpu}
+
+		code := http.StatusServiceUnavailable
+		checked, err := r.checkHealth()
+		switch {
+		case r.closed.Load():
+			status.Error = "runtime is closed"
+		case !checked:
+			status.Status = "initializing"
+		case err != nil:
+			status.Error = err.Error()
+		default:
+			code = http.StatusOK
+			status.Status = "ok"
+			status.Version = ort.GetVersion()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// checkHealth returns the result of the last background health check, starting one if
+// none has run yet or the last one failed. checked is false while the first is running.
+func (r *Runtime) checkHealth() (checked bool, err error) {
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+
+	if !r.healthRunning && (!r.healthChecked || r.healthErr != nil) && !r.closed.Load() {
+		r.healthRunning = true
+		go func() {
+			err := r.Initialize(context.Background())
+			if err == nil {
+				err = r.SelfTest()
+			}
+
+			r.healthMu.Lock()
+			defer r.healthMu.Unlock()
+			r.healthRunning = false
+			r.healthChecked = true
+			r.healthErr = err
+		}()
+	}
+	return r.healthChecked, r.healthErr
+}
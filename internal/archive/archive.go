@@ -4,12 +4,126 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
+// ErrNotFound is returned when the requested file is not in the archive
+var ErrNotFound = errors.New("not found in archive")
+
+// ErrWrite is returned when an extracted file cannot be written to disk
+var ErrWrite = errors.New("failed to write extracted file")
+
+// Extractor extracts targetFile from the archive at archivePath to destPath
+type Extractor func(archivePath, destPath, targetFile string) error
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Extractor{
+		".zip":    ExtractFromZip,
+		".tgz":    ExtractFromTarGz,
+		".tar.gz": ExtractFromTarGz,
+	}
+)
+
+// Register adds or replaces the extractor for archives whose name ends with ext
+func Register(ext string, extractor Extractor) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[ext] = extractor
+}
+
+// Extract extracts a specific file from an archive using the extractor registered for
+// the longest matching extension, falling back to tar.gz
+func Extract(archivePath, destPath, targetFile string) error {
+	formatsMu.RLock()
+	extractor, matched := Extractor(ExtractFromTarGz), ""
+	for ext, e := range formats {
+		if strings.HasSuffix(archivePath, ext) && len(ext) > len(matched) {
+			extractor, matched = e, ext
+		}
+	}
+	formatsMu.RUnlock()
+
+	return extractor(archivePath, destPath, targetFile)
+}
+
+// ExtractNested extracts a specific file like Extract, and when it is not found at the
+// top level, searches archives nested inside it up to depth levels deep
+func ExtractNested(archivePath, destPath, targetFile string, depth int) error {
+	err := Extract(archivePath, destPath, targetFile)
+	if !errors.Is(err, ErrNotFound) || depth <= 0 {
+		return err
+	}
+
+	found := false
+	walkErr := walkNested(archivePath, filepath.Dir(destPath), func(nestedPath string) (bool, error) {
+		err := ExtractNested(nestedPath, destPath, targetFile, depth-1)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		found = err == nil
+		return found, err
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	if !found {
+		return err
+	}
+	return nil
+}
+
+// List returns the names of all entries in a zip or tar.gz archive
+func List(archivePath string) ([]string, error) {
+	var names []string
+
+	if strings.HasSuffix(archivePath, ".zip") {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+		}
+		return names, nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, header.Name)
+	}
+}
+
 // ExtractFromZip extracts a specific file from a zip archive
 func ExtractFromZip(archivePath, destPath, targetFile string) error {
 	reader, err := zip.OpenReader(archivePath)
@@ -19,35 +133,102 @@ func ExtractFromZip(archivePath, destPath, targetFile string) error {
 	defer reader.Close()
 
 	for _, file := range reader.File {
-		if strings.HasSuffix(file.Name, targetFile) {
-			reader, err := file.Open()
-			if err != nil {
-				return err
+		if matches(file.Name, targetFile) {
+			if err := extractZipFile(file, destPath); err != nil {
+				return fmt.Errorf("entry %s: %w", file.Name, err)
 			}
-			defer reader.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("file %s %w", targetFile, ErrNotFound)
+}
 
-			writer, err := os.Create(destPath)
-			if err != nil {
-				return err
-			}
-			defer writer.Close()
+// ExtractFromTarGz extracts a specific file from a tar.gz archive
+func ExtractFromTarGz(archivePath, destPath, targetFile string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ExtractFromTarGzReader(file, destPath, targetFile)
+}
+
+// ExtractFromTarGzReader extracts a specific file from a tar.gz stream
+func ExtractFromTarGzReader(r io.Reader, destPath, targetFile string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
 
-			_, err = io.Copy(writer, reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
 			return err
 		}
+
+		if matches(header.Name, targetFile) {
+			if err := writeFile(tr, destPath); err != nil {
+				return fmt.Errorf("entry %s: %w", header.Name, err)
+			}
+			return nil
+		}
 	}
-	return fmt.Errorf("file %s not found in archive", targetFile)
+	return fmt.Errorf("file %s %w", targetFile, ErrNotFound)
 }
 
-// ExtractFromTarGz extracts a specific file from a tar.gz archive
-func ExtractFromTarGz(archivePath, destPath, targetFile string) error {
+// ExtractDirFromZip extracts every file under a directory named dirName in a zip archive into destDir,
+// except those whose base name skip reports true for. A nil skip extracts everything.
+func ExtractDirFromZip(archivePath, destDir, dirName string, skip func(name string) bool) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	found := false
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !inDir(file.Name, dirName) {
+			continue
+		}
+		if skip != nil && skip(path.Base(file.Name)) {
+			continue
+		}
+
+		if err := extractZipFile(file, filepath.Join(destDir, path.Base(file.Name))); err != nil {
+			return fmt.Errorf("entry %s: %w", file.Name, err)
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("directory %s %w", dirName, ErrNotFound)
+	}
+	return nil
+}
+
+// ExtractDirFromTarGz extracts every file under a directory named dirName in a tar.gz archive into destDir,
+// except those whose base name skip reports true for. A nil skip extracts everything.
+func ExtractDirFromTarGz(archivePath, destDir, dirName string, skip func(name string) bool) error {
 	file, err := os.Open(archivePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	gzr, err := gzip.NewReader(file)
+	return ExtractDirFromTarGzReader(file, destDir, dirName, skip)
+}
+
+// ExtractDirFromTarGzReader extracts every file under a directory named dirName in a tar.gz stream into destDir,
+// except those whose base name skip reports true for. A nil skip extracts everything.
+func ExtractDirFromTarGzReader(r io.Reader, destDir, dirName string, skip func(name string) bool) error {
+	gzr, err := gzip.NewReader(r)
 	if err != nil {
 		return err
 	}
@@ -55,6 +236,7 @@ func ExtractFromTarGz(archivePath, destPath, targetFile string) error {
 
 	tr := tar.NewReader(gzr)
 
+	found := false
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -64,16 +246,186 @@ func ExtractFromTarGz(archivePath, destPath, targetFile string) error {
 			return err
 		}
 
-		if strings.HasSuffix(header.Name, targetFile) {
-			writer, err := os.Create(destPath)
-			if err != nil {
+		if !inDir(header.Name, dirName) {
+			continue
+		}
+		if skip != nil && skip(path.Base(header.Name)) {
+			continue
+		}
+
+		destPath := filepath.Join(destDir, path.Base(header.Name))
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			if err := writeFile(tr, destPath); err != nil {
+				return fmt.Errorf("entry %s: %w", header.Name, err)
+			}
+		case tar.TypeSymlink:
+			// Sibling links like libonnxruntime.so -> libonnxruntime.so.1.20.0 are kept,
+			// anything pointing outside the directory is skipped
+			if strings.ContainsAny(header.Linkname, `/\`) {
+				continue
+			}
+			os.Remove(destPath)
+			if err := os.Symlink(header.Linkname, destPath); err != nil {
+				return fmt.Errorf("entry %s: %w: %w", header.Name, ErrWrite, err)
+			}
+		default:
+			continue
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("directory %s %w", dirName, ErrNotFound)
+	}
+	return nil
+}
+
+func extractZipFile(file *zip.File, destPath string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return writeFile(reader, destPath)
+}
+
+// writeFile writes to a temporary file and renames it into place, so a failed
+// extraction never leaves a partial file at destPath. Failures on the destination
+// side are wrapped in ErrWrite, reading the archive returns its error as-is.
+func writeFile(reader io.Reader, destPath string) error {
+	tmpFile := destPath + ".extract"
+	defer os.Remove(tmpFile)
+
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWrite, err)
+	}
+	defer f.Close()
+
+	writer := &writeTracker{w: f}
+	if _, err := io.Copy(writer, reader); err != nil {
+		if writer.err != nil {
+			return fmt.Errorf("%w: %w", ErrWrite, err)
+		}
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrWrite, err)
+	}
+	if err := os.Rename(tmpFile, destPath); err != nil {
+		return fmt.Errorf("%w: %w", ErrWrite, err)
+	}
+	return nil
+}
+
+// writeTracker records whether an error came from the underlying writer
+type writeTracker struct {
+	w   io.Writer
+	err error
+}
+
+func (t *writeTracker) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if err != nil {
+		t.err = err
+	}
+	return n, err
+}
+
+// matches reports whether an archive entry is targetFile, either as its full path
+// inside the archive or as a trailing sequence of path components
+func matches(name, targetFile string) bool {
+	targetFile = strings.TrimPrefix(targetFile, "/")
+	return name == targetFile || strings.HasSuffix(name, "/"+targetFile)
+}
+
+// inDir reports whether an archive entry lives directly inside a directory named dirName
+func inDir(name, dirName string) bool {
+	return path.Base(path.Dir(strings.TrimSuffix(name, "/"))) == dirName
+}
+
+// walkNested writes each archive nested inside archivePath to a temporary file in tmpDir
+// and calls fn with its path, stopping when fn returns true or an error
+func walkNested(archivePath, tmpDir string, fn func(nestedPath string) (bool, error)) error {
+	visit := func(name string, write func(destPath string) error) (bool, error) {
+		tmp, err := os.CreateTemp(tmpDir, "nested-*"+nestedExt(name))
+		if err != nil {
+			return false, err
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+
+		if err := write(tmp.Name()); err != nil {
+			return false, err
+		}
+		return fn(tmp.Name())
+	}
+
+	if strings.HasSuffix(archivePath, ".zip") {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		for _, file := range reader.File {
+			if nestedExt(file.Name) == "" {
+				continue
+			}
+
+			stop, err := visit(file.Name, func(destPath string) error { return extractZipFile(file, destPath) })
+			if err != nil || stop {
 				return err
 			}
-			defer writer.Close()
+		}
+		return nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
 
-			_, err = io.Copy(writer, tr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
 			return err
 		}
+
+		if header.Typeflag != tar.TypeReg || nestedExt(header.Name) == "" {
+			continue
+		}
+
+		stop, err := visit(header.Name, func(destPath string) error { return writeFile(tr, destPath) })
+		if err != nil || stop {
+			return err
+		}
+	}
+}
+
+// nestedExt returns the archive extension of name, or an empty string if it isn't an archive
+func nestedExt(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ".zip"
+	case strings.HasSuffix(name, ".tgz"), strings.HasSuffix(name, ".tar.gz"):
+		return ".tgz"
 	}
-	return fmt.Errorf("file %s not found in archive", targetFile)
+	return ""
 }
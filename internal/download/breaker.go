@@ -0,0 +1,111 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the server while a Breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Breaker stops requests to a failing server. After threshold consecutive failures
+// within window it opens, failing requests with ErrCircuitOpen until cooldown has
+// passed. The next request is then let through and a failure reopens it at once.
+type Breaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	mu           sync.Mutex
+	failures     int
+	firstFailure time.Time
+	openUntil    time.Time
+}
+
+// NewBreaker creates a Breaker, see Breaker for the meaning of the parameters
+func NewBreaker(threshold int, window, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, window: window, cooldown: cooldown}
+}
+
+// WithBreaker routes requests through b
+func WithBreaker(b *Breaker) Option {
+	return func(c *config) { c.breaker = b }
+}
+
+// allow returns ErrCircuitOpen while the breaker is open. A nil Breaker always allows.
+func (b *Breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Now().Before(b.openUntil) {
+		return fmt.Errorf("%w: retry after %s", ErrCircuitOpen, b.openUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a request. A 404 shows the server
+// is up, and cancellation says nothing about it, so neither counts as a failure.
+func (b *Breaker) record(err error) {
+	if b == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || errors.Is(err, ErrNotFound) {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	now := time.Now()
+	if !b.openUntil.IsZero() {
+		b.openUntil = now.Add(b.cooldown)
+		return
+	}
+
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	b.failures++
+
+	if b.failures >= b.threshold {
+		b.failures = 0
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// send performs req unless the breaker is open and checks the response status,
+// reporting the outcome to the breaker
+func (c *config) send(req *http.Request) (*http.Response, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.breaker.record(err)
+		if req.Method == http.MethodHead {
+			return nil, fmt.Errorf("failed to fetch file info: %w", err)
+		}
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	if err := checkStatus(resp); err != nil {
+		resp.Body.Close()
+		c.breaker.record(err)
+		return nil, err
+	}
+	c.breaker.record(nil)
+	return resp, nil
+}
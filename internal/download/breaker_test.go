@@ -0,0 +1,67 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBreakerTripAndReset(t *testing.T) {
+	b := NewBreaker(3, time.Minute, 50*time.Millisecond)
+	failure := errors.New("connection refused")
+
+	for i := 0; i < 2; i++ {
+		b.record(failure)
+		if err := b.allow(); err != nil {
+			t.Fatalf("open after %d failures: %v", i+1, err)
+		}
+	}
+	b.record(failure)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after 3 failures, got %v", err)
+	}
+
+	// After the cooldown one request goes through, and its failure reopens at once
+	time.Sleep(60 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatalf("still open after cooldown: %v", err)
+	}
+	b.record(failure)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a failed probe to reopen, got %v", err)
+	}
+
+	// A successful probe closes it and clears the failure count
+	time.Sleep(60 * time.Millisecond)
+	b.record(nil)
+	for i := 0; i < 2; i++ {
+		b.record(failure)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("open after a reset and 2 failures: %v", err)
+	}
+}
+
+func TestBreakerIgnoresNotFoundAndCancel(t *testing.T) {
+	b := NewBreaker(1, time.Minute, time.Minute)
+
+	b.record(context.Canceled)
+	b.record(fmt.Errorf("%w: 404", ErrNotFound))
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected closed breaker, got %v", err)
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	b := NewBreaker(2, 20*time.Millisecond, time.Minute)
+	failure := errors.New("connection refused")
+
+	b.record(failure)
+	time.Sleep(30 * time.Millisecond)
+	b.record(failure)
+	if err := b.allow(); err != nil {
+		t.Fatalf("failures outside the window tripped the breaker: %v", err)
+	}
+}
@@ -2,14 +2,106 @@ package download
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 )
 
-func DownloadFile(ctx context.Context, url string, destPath string) (string, error) {
-	client := http.DefaultClient
+// ErrShortDownload is returned when fewer bytes were received than the server advertised
+var ErrShortDownload = errors.New("download incomplete")
+
+// ErrNotFound is returned when the server responds with 404 Not Found
+var ErrNotFound = errors.New("not found")
+
+// ErrUnexpectedContent is returned when the server responds with an HTML page instead of a file
+var ErrUnexpectedContent = errors.New("unexpected content")
+
+// Option is a functional option for configuring a download
+type Option func(*config)
+
+type config struct {
+	client     *http.Client
+	header     http.Header
+	bufferSize int
+	breaker    *Breaker
+}
+
+// WithClient sets the HTTP client used for the request
+func WithClient(client *http.Client) Option {
+	return func(c *config) { c.client = client }
+}
+
+// WithHeader adds a header to the request
+func WithHeader(key, value string) Option {
+	return func(c *config) { c.header.Add(key, value) }
+}
+
+// WithBufferSize sets the size of the buffer used to copy the response body to disk
+func WithBufferSize(size int) Option {
+	return func(c *config) { c.bufferSize = size }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{client: http.DefaultClient, header: http.Header{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// copy copies src to dst using the configured buffer size, or io.Copy's default if unset
+func (c *config) copy(dst io.Writer, src io.Reader) (int64, error) {
+	if c.bufferSize <= 0 {
+		return io.Copy(dst, src)
+	}
+	// Hide any ReaderFrom implementation so the buffer is actually used
+	return io.CopyBuffer(struct{ io.Writer }{dst}, src, make([]byte, c.bufferSize))
+}
+
+// checkContentType rejects HTML responses, typically a login or error page a
+// misconfigured mirror redirected to while still answering 200 OK
+func checkContentType(resp *http.Response) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/html" {
+		return fmt.Errorf("%w: %s returned an HTML page", ErrUnexpectedContent, resp.Request.URL)
+	}
+	return nil
+}
+
+// checkStatus returns an error for any response other than 200 OK
+func checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: unexpected status code: %d", ErrNotFound, resp.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+func (c *config) newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	return req, nil
+}
+
+func DownloadFile(ctx context.Context, url string, destPath string, opts ...Option) (string, error) {
+	return DownloadParts(ctx, []string{url}, destPath, opts...)
+}
+
+// DownloadParts downloads each URL in order and concatenates the bodies into destPath,
+// for archives a mirror has split into several files
+func DownloadParts(ctx context.Context, urls []string, destPath string, opts ...Option) (string, error) {
+	c := newConfig(opts)
 
 	tmpFile := destPath + ".download"
 	defer os.Remove(tmpFile)
@@ -20,27 +112,113 @@ func DownloadFile(ctx context.Context, url string, destPath string) (string, err
 	}
 	defer f.Close()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	for _, url := range urls {
+		if err := c.fetch(ctx, url, f); err != nil {
+			return "", err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile, destPath); err != nil {
+		return "", fmt.Errorf("failed to move downloaded file: %w", err)
+	}
+	return destPath, nil
+}
+
+// fetch downloads url and writes the body to w
+func (c *config) fetch(ctx context.Context, url string, w io.Writer) error {
+	req, err := c.newRequest(ctx, "GET", url)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to download file: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if err := checkContentType(resp); err != nil {
+		return err
 	}
 
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
+	n, err := c.copy(w, resp.Body)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrShortDownload, n, resp.ContentLength)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
 	}
 
-	if err := os.Rename(tmpFile, destPath); err != nil {
-		return "", fmt.Errorf("failed to move downloaded file: %w", err)
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrShortDownload, n, resp.ContentLength)
 	}
-	return destPath, nil
+	return nil
+}
+
+// ContentLength performs a HEAD request and returns the advertised size, or -1 if unknown
+func ContentLength(ctx context.Context, url string, opts ...Option) (int64, error) {
+	c := newConfig(opts)
+
+	req, err := c.newRequest(ctx, "HEAD", url)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+// Stream requests url and passes the response body to fn, for processing a download
+// without writing it to disk. fn must consume the whole body for the size to be verified.
+func Stream(ctx context.Context, url string, fn func(body io.Reader) error, opts ...Option) error {
+	c := newConfig(opts)
+
+	req, err := c.newRequest(ctx, "GET", url)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkContentType(resp); err != nil {
+		return err
+	}
+
+	body := &countingReader{r: resp.Body}
+
+	err = fn(body)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrShortDownload, body.n, resp.ContentLength)
+	}
+	if err != nil {
+		return err
+	}
+
+	if resp.ContentLength >= 0 && body.n != resp.ContentLength {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrShortDownload, body.n, resp.ContentLength)
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
 }
@@ -0,0 +1,269 @@
+package model
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Field numbers from onnx.proto
+const (
+	modelGraph     = 7
+	modelFunctions = 25
+
+	graphNode        = 1
+	graphInitializer = 5
+
+	nodeInput     = 1
+	nodeOpType    = 4
+	nodeAttribute = 5
+	nodeDomain    = 7
+
+	attributeGraph  = 6
+	attributeGraphs = 11
+
+	functionNode = 7
+
+	tensorDims     = 1
+	tensorDataType = 2
+	tensorName     = 8
+)
+
+// Node is an operator invocation in a graph
+type Node struct {
+	OpType string
+	Domain string
+	Inputs []string
+}
+
+// Tensor describes a constant tensor stored in the model
+type Tensor struct {
+	Name     string
+	DataType int32
+	Dims     []int64
+}
+
+// Model is the subset of an ONNX ModelProto needed for inspection
+type Model struct {
+	// Nodes holds every node in the main graph, nested subgraphs and local functions
+	Nodes []Node
+
+	// Initializers holds the weights of the main graph and nested subgraphs
+	Initializers []Tensor
+}
+
+// Load reads and parses an ONNX model file
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a serialized ONNX ModelProto
+func Parse(data []byte) (*Model, error) {
+	m := &Model{}
+	hasGraph := false
+
+	err := walk(data, func(num int, value []byte) error {
+		switch num {
+		case modelGraph:
+			hasGraph = true
+			return m.parseGraph(value, 0)
+		case modelFunctions:
+			return m.parseFunction(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid model: %w", err)
+	}
+	// Any byte string without fields parses, e.g. an empty file
+	if !hasGraph {
+		return nil, errors.New("invalid model: no graph")
+	}
+	return m, nil
+}
+
+func (m *Model) parseGraph(data []byte, depth int) error {
+	if depth > maxGraphDepth {
+		return errTooDeep
+	}
+	return walk(data, func(num int, value []byte) error {
+		switch num {
+		case graphNode:
+			return m.parseNode(value, depth)
+		case graphInitializer:
+			return m.parseTensor(value)
+		}
+		return nil
+	})
+}
+
+func (m *Model) parseTensor(data []byte) error {
+	var tensor Tensor
+
+	err := scan(data, func(f field) error {
+		switch {
+		case f.num == tensorName && f.wireType == wireBytes:
+			tensor.Name = string(f.value)
+		case f.num == tensorDataType && f.wireType == wireVarint:
+			tensor.DataType = int32(f.varint)
+		case f.num == tensorDims && f.wireType == wireVarint:
+			tensor.Dims = append(tensor.Dims, int64(f.varint))
+		case f.num == tensorDims && f.wireType == wireBytes:
+			// Packed repeated int64
+			for packed := f.value; len(packed) > 0; {
+				dim, n := binary.Uvarint(packed)
+				if n <= 0 {
+					return errTruncated
+				}
+				tensor.Dims = append(tensor.Dims, int64(dim))
+				packed = packed[n:]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.Initializers = append(m.Initializers, tensor)
+	return nil
+}
+
+// parseFunction collects the nodes of a local function. An empty node domain means the
+// default ONNX domain, not the function's own domain.
+func (m *Model) parseFunction(data []byte) error {
+	return walk(data, func(num int, value []byte) error {
+		if num == functionNode {
+			return m.parseNode(value, 0)
+		}
+		return nil
+	})
+}
+
+// parseNode collects a node and the subgraphs in its attributes, depth being the nesting
+// level of the graph containing it
+func (m *Model) parseNode(data []byte, depth int) error {
+	var node Node
+	var attributes [][]byte
+
+	err := walk(data, func(num int, value []byte) error {
+		switch num {
+		case nodeInput:
+			node.Inputs = append(node.Inputs, string(value))
+		case nodeOpType:
+			node.OpType = string(value)
+		case nodeDomain:
+			node.Domain = string(value)
+		case nodeAttribute:
+			attributes = append(attributes, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.Nodes = append(m.Nodes, node)
+
+	// Control flow ops (If, Loop, Scan) carry subgraphs in their attributes
+	for _, attribute := range attributes {
+		err := walk(attribute, func(num int, value []byte) error {
+			if num == attributeGraph || num == attributeGraphs {
+				return m.parseGraph(value, depth+1)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// maxGraphDepth bounds subgraph nesting, so a hostile model can't exhaust the stack
+const maxGraphDepth = 64
+
+var errTooDeep = fmt.Errorf("subgraphs nested more than %d levels deep", maxGraphDepth)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// field is a single protobuf field. Varint fields set varint, length-delimited fields set value.
+type field struct {
+	num      int
+	wireType uint64
+	varint   uint64
+	value    []byte
+}
+
+// walk calls fn for every length-delimited field in a protobuf message, skipping other wire types
+func walk(data []byte, fn func(num int, value []byte) error) error {
+	return scan(data, func(f field) error {
+		if f.wireType == wireBytes {
+			return fn(f.num, f.value)
+		}
+		return nil
+	})
+}
+
+// scan calls fn for every varint and length-delimited field in a protobuf message,
+// skipping fixed-size fields
+func scan(data []byte, fn func(f field) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+
+		f := field{num: int(key >> 3), wireType: key & 7}
+
+		switch f.wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+
+			f.varint = v
+			if err := fn(f); err != nil {
+				return err
+			}
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			data = data[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return errTruncated
+			}
+			f.value = data[n : n+int(size)]
+			data = data[n+int(size):]
+
+			if err := fn(f); err != nil {
+				return err
+			}
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", f.wireType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package onnx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/joeychilson/onnx/internal/archive"
+)
+
+// licenseFiles are the compliance files shipped at the top level of every runtime archive
+var licenseFiles = []string{"LICENSE", "ThirdPartyNotices.txt", "VERSION_NUMBER"}
+
+// ExtractLicenses extracts the runtime archive's LICENSE, ThirdPartyNotices.txt and
+// VERSION_NUMBER files into destDir. The archive is downloaded again if it was already
+// cleaned up after extracting the library.
+func (r *Runtime) ExtractLicenses(ctx context.Context, destDir string) error {
+	if err := r.resolveVersion(ctx); err != nil {
+		return err
+	}
+
+	info := r.RuntimeInfo()
+	if info.Arch == "" {
+		return r.unsupportedPlatform()
+	}
+
+	_, _, archivePath := r.runtimePaths(info)
+
+	_, statErr := os.Stat(archivePath)
+	cached := statErr == nil
+
+	if !cached {
+		if err := r.mkdirCache(filepath.Dir(archivePath)); err != nil {
+			return err
+		}
+	}
+
+	if err := r.fetchArchive(ctx, r.runtimeURLs(info), archivePath); err != nil {
+		return err
+	}
+
+	if !cached {
+		defer os.Remove(archivePath)
+	}
+
+	if err := r.mkdirCache(destDir); err != nil {
+		return err
+	}
+
+	entries, err := archive.List(archivePath)
+	if err != nil {
+		return fmt.Errorf("%w from %s: %w", ErrExtract, archivePath, err)
+	}
+
+	for _, name := range licenseFiles {
+		entry, ok := topLevelEntry(entries, name)
+		if !ok {
+			return fmt.Errorf("%w from %s: file %s %w", ErrExtract, archivePath, name, ErrArchiveEntryNotFound)
+		}
+		if err := archive.Extract(archivePath, filepath.Join(destDir, name), entry); err != nil {
+			return fmt.Errorf("%w from %s: %w", ErrExtract, archivePath, err)
+		}
+	}
+	return nil
+}
+
+// topLevelEntry returns the entry for name directly inside the archive's root directory,
+// skipping files of the same name deeper in the tree like include/.../LICENSE
+func topLevelEntry(entries []string, name string) (string, bool) {
+	for _, entry := range entries {
+		dir, base := path.Split(strings.TrimPrefix(entry, "./"))
+		if base == name && dir != "" && strings.Count(dir, "/") == 1 {
+			return entry, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,110 @@
+package onnx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	// lockPollInterval is how often a waiting process checks whether the lock was released
+	lockPollInterval = 100 * time.Millisecond
+
+	// lockHeartbeat is how often the holder refreshes the lock file's modification time
+	lockHeartbeat = 30 * time.Second
+
+	// lockStaleAfter is how long a lock may go unrefreshed before its holder is presumed dead
+	lockStaleAfter = 4 * lockHeartbeat
+)
+
+// acquireLock creates path as a lock file shared between processes, waiting while another
+// process holds it until ctx is done. A lock not refreshed for lockStaleAfter is taken
+// over to recover from crashed holders. The returned func releases the lock.
+func acquireLock(ctx context.Context, path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return holdLock(path), nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if _, stale := isStaleLock(path); stale && removeStaleLock(path) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for cache lock %s: %w", path, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// isStaleLock reports whether the lock file at path exists and hasn't been refreshed for
+// lockStaleAfter, and how long ago it was
+func isStaleLock(path string) (time.Duration, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	age := time.Since(info.ModTime())
+	return age, age > lockStaleAfter
+}
+
+// removeStaleLock removes a stale lock file and reports whether it did. Waiters take turns
+// through a takeover lock and check again once they hold it, so a waiter that judged the
+// lock stale can't remove the fresh lock another waiter has created since.
+func removeStaleLock(path string) bool {
+	takeover := path + ".takeover"
+
+	f, err := os.OpenFile(takeover, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		// A waiter that crashed mid-takeover leaves this behind
+		if _, stale := isStaleLock(takeover); stale {
+			os.Remove(takeover)
+		}
+		return false
+	}
+	f.Close()
+	defer os.Remove(takeover)
+
+	age, stale := isStaleLock(path)
+	if !stale {
+		return false
+	}
+
+	slog.Warn("onnx: removing stale cache lock", "path", path, "age", age)
+	return os.Remove(path) == nil
+}
+
+// holdLock refreshes the lock file until released, so a long download isn't taken for a crash
+func holdLock(path string) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(lockHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				os.Chtimes(path, now, now)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,93 @@
+package onnx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAcquireLockTakeover takes over a lock whose holder stopped refreshing it
+func TestAcquireLockTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.lock")
+	if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * lockStaleAfter)
+	if err := os.Chtimes(path, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	unlock, err := acquireLock(ctx, path)
+	if err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	if _, stale := isStaleLock(path); stale {
+		t.Fatal("lock was taken over without being refreshed")
+	}
+	if _, err := os.Stat(path + ".takeover"); err == nil {
+		t.Fatal("takeover lock was left behind")
+	}
+
+	unlock()
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("lock was not released")
+	}
+}
+
+// TestAcquireLockWaits waits on a live lock instead of taking it over
+func TestAcquireLockWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.lock")
+	unlock, err := acquireLock(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+	defer cancel()
+	if _, err := acquireLock(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to time out, got %v", err)
+	}
+
+	unlock()
+	unlock, err = acquireLock(context.Background(), path)
+	if err != nil {
+		t.Fatalf("acquireLock after release: %v", err)
+	}
+	unlock()
+}
+
+// TestRemoveStaleLockInTakeover leaves the lock alone while another waiter holds the
+// takeover lock, and clears a takeover lock left by a crashed waiter
+func TestRemoveStaleLockInTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.lock")
+	stale := time.Now().Add(-2 * lockStaleAfter)
+	for _, p := range []string{path, path + ".takeover"} {
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(path, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if removeStaleLock(path) {
+		t.Fatal("stale lock removed during another waiter's takeover")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("lock was removed: %v", err)
+	}
+
+	if err := os.Chtimes(path+".takeover", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	removeStaleLock(path)
+	if !removeStaleLock(path) {
+		t.Fatal("stale lock not removed after clearing the crashed takeover")
+	}
+}
@@ -0,0 +1,78 @@
+package onnx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// WithChecksumManifest enforces SHA256 checksums from a JSON manifest on downloaded archives.
+// The manifest maps archive file names, which encode os, arch, gpu and version, to hex digests:
+//
+//	{
+//	  "onnxruntime-linux-x64-1.20.0.tgz": "<sha256>",
+//	  "onnxruntime-linux-x64-gpu-1.20.0.tgz": "<sha256>"
+//	}
+//
+// Use os.DirFS to load a manifest from disk.
+func WithChecksumManifest(fsys fs.FS, name string) Option {
+	return func(r *Runtime) {
+		r.manifestFS = fsys
+		r.manifestName = name
+	}
+}
+
+// verifyArchive checks the archive against the checksum manifest, if one is configured.
+// names are the asset names the archive may have been downloaded as; the first one
+// listed in the manifest is checked.
+func (r *Runtime) verifyArchive(archivePath string, names []string) error {
+	if r.manifestFS == nil {
+		return nil
+	}
+
+	data, err := fs.ReadFile(r.manifestFS, r.manifestName)
+	if err != nil {
+		return fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse checksum manifest: %w", err)
+	}
+
+	for _, name := range names {
+		expected, ok := manifest[name]
+		if !ok {
+			continue
+		}
+
+		actual, err := fileSHA256(archivePath)
+		if err != nil {
+			return fmt.Errorf("failed to compute checksum: %w", err)
+		}
+		if !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("%w: %s has %s, expected %s", ErrChecksumMismatch, name, actual, expected)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no checksum for %s in manifest", ErrChecksumMismatch, strings.Join(names, " or "))
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
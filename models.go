@@ -0,0 +1,283 @@
+package onnx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joeychilson/onnx/internal/archive"
+	"github.com/joeychilson/onnx/internal/download"
+	"github.com/joeychilson/onnx/internal/model"
+)
+
+const huggingFaceBaseURL = "https://huggingface.co"
+
+// WithHuggingFaceToken sets the access token used to download models from gated Hugging Face repositories
+func WithHuggingFaceToken(token string) Option {
+	return func(r *Runtime) { r.hfToken = token }
+}
+
+// WithHuggingFaceRevision sets the branch, tag or commit to download Hugging Face models from
+func WithHuggingFaceRevision(revision string) Option {
+	return func(r *Runtime) { r.hfRevision = revision }
+}
+
+// WithModelCacheDir sets where downloaded models are cached, separately from the runtime.
+// Defaults to the models directory of the cache path.
+func WithModelCacheDir(path string) Option {
+	return func(r *Runtime) { r.modelCacheDir = path }
+}
+
+// WithModelCacheLimit caps the total size of downloaded models in bytes. When a model
+// is accessed, least recently used models are deleted until the cache fits, except the
+// model being returned. ONNX Runtime reads a model fully when a session is created, so
+// evicting the file of an open session is safe.
+func WithModelCacheLimit(bytes int64) Option {
+	return func(r *Runtime) { r.modelCacheLimit = bytes }
+}
+
+// modelsDir returns the directory downloaded models are cached in
+func (r *Runtime) modelsDir() string {
+	if r.modelCacheDir != "" {
+		return r.modelCacheDir
+	}
+	return filepath.Join(r.cachePath, "models")
+}
+
+// DownloadHuggingFaceModel downloads a file from a Hugging Face model repository
+// (e.g. "onnx-community/bert-base-uncased", "onnx/model.onnx") into the models cache
+// and returns its path. Cached files are returned without contacting the Hub.
+func DownloadHuggingFaceModel(ctx context.Context, repo, file string, opts ...Option) (string, error) {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	revision := r.hfRevision
+	if revision == "" {
+		revision = "main"
+	}
+
+	for _, part := range []string{repo, file, revision} {
+		if part == "" || strings.HasPrefix(part, "/") || strings.Contains(part, "..") {
+			return "", fmt.Errorf("invalid hugging face path: %q", part)
+		}
+	}
+
+	modelPath := filepath.Join(r.modelsDir(), "huggingface", filepath.FromSlash(repo), revision, filepath.FromSlash(file))
+	if _, err := os.Stat(modelPath); err == nil {
+		r.observeCacheHit(modelPath)
+		r.touchModel(modelPath)
+		return modelPath, nil
+	}
+
+	modelURL := fmt.Sprintf("%s/%s/resolve/%s/%s", huggingFaceBaseURL, repo, url.PathEscape(revision), file)
+
+	downloadOpts := r.downloadOptions()
+	if r.hfToken != "" {
+		downloadOpts = append(downloadOpts, download.WithHeader("Authorization", "Bearer "+r.hfToken))
+	}
+
+	if err := r.fetchModel(ctx, modelURL, modelPath, downloadOpts); err != nil {
+		return "", err
+	}
+
+	r.touchModel(modelPath)
+	return modelPath, nil
+}
+
+// DownloadModel downloads an ONNX model from an HTTP(S) URL into the models cache and
+// returns its path, for creating a session with onnxruntime_go. The file is checked to
+// parse as an ONNX model. Cached files are returned without contacting the server.
+func DownloadModel(ctx context.Context, modelURL string, opts ...Option) (string, error) {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(modelURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid model url: %q", modelURL)
+	}
+	if strings.Contains(u.Path, "..") || strings.HasSuffix(u.Path, "/") || u.Path == "" {
+		return "", fmt.Errorf("invalid model url: %q", modelURL)
+	}
+
+	modelPath := filepath.Join(r.modelsDir(), "url", u.Host, filepath.FromSlash(u.Path))
+	if _, err := os.Stat(modelPath); err == nil {
+		r.observeCacheHit(modelPath)
+		r.touchModel(modelPath)
+		return modelPath, nil
+	}
+
+	if err := r.fetchModel(ctx, modelURL, modelPath, r.downloadOptions()); err != nil {
+		return "", err
+	}
+
+	if _, err := model.Load(modelPath); err != nil {
+		os.Remove(modelPath)
+		return "", fmt.Errorf("%s is not an ONNX model: %w", modelURL, err)
+	}
+
+	r.touchModel(modelPath)
+	return modelPath, nil
+}
+
+// fetchModel downloads modelURL to modelPath in the models cache
+func (r *Runtime) fetchModel(ctx context.Context, modelURL, modelPath string, downloadOpts []download.Option) error {
+	if err := r.mkdirCache(filepath.Dir(modelPath)); err != nil {
+		return err
+	}
+
+	err := r.observeDownload(modelURL, func() error {
+		_, err := download.DownloadFile(ctx, modelURL, modelPath, downloadOpts...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDownload, err)
+	}
+
+	if err := os.Chmod(modelPath, r.cacheFileMode); err != nil {
+		return fmt.Errorf("failed to set model permissions: %w", err)
+	}
+	return nil
+}
+
+// touchModel records an access to a cached model and evicts least recently used
+// models if the cache exceeds its limit. Failures are logged, not returned, since
+// the model itself is usable either way.
+func (r *Runtime) touchModel(modelPath string) {
+	if r.modelCacheLimit <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(modelPath, now, now); err != nil {
+		slog.Warn("onnx: failed to record model access", "path", modelPath, "error", err)
+	}
+
+	if err := evictModels(r.modelsDir(), r.modelCacheLimit, modelPath); err != nil {
+		slog.Warn("onnx: failed to evict cached models", "error", err)
+	}
+}
+
+// managedModelDirs are the subdirectories of the models directory this package writes to.
+// Nothing else is evicted, since WithModelCacheDir may point at a shared data volume.
+var managedModelDirs = []string{"huggingface", "url", "archive"}
+
+// evictModels deletes the least recently used models in the managed subdirectories of dir
+// until their total size is within limit. Access is tracked by modification time. keep and
+// in-flight temporary files are never evicted.
+func evictModels(dir string, limit int64, keep string) error {
+	type cachedModel struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var models []cachedModel
+	var total int64
+
+	for _, sub := range managedModelDirs {
+		err := filepath.WalkDir(filepath.Join(dir, sub), func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() || isTransientCacheFile(d.Name()) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			models = append(models, cachedModel{path: path, size: info.Size(), modTime: info.ModTime()})
+			total += info.Size()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	slices.SortFunc(models, func(a, b cachedModel) int { return a.modTime.Compare(b.modTime) })
+
+	for _, model := range models {
+		if total <= limit {
+			break
+		}
+		if model.path == keep {
+			continue
+		}
+		if err := os.Remove(model.path); err != nil {
+			return err
+		}
+		total -= model.size
+	}
+	return nil
+}
+
+// ExtractModel extracts an entry of a zip or tar.gz model bundle (e.g. "model.onnx")
+// into the models cache and returns its path, for creating a session with onnxruntime_go.
+// Sibling files such as a labels.txt can be extracted the same way. The entry is
+// extracted again when the archive is newer than the cached copy.
+func ExtractModel(archivePath, entry string, opts ...Option) (string, error) {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	entry = strings.TrimPrefix(entry, "/")
+	if entry == "" || strings.Contains(entry, "..") {
+		return "", fmt.Errorf("invalid archive entry: %q", entry)
+	}
+
+	archiveStat, err := os.Stat(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open model archive: %w", err)
+	}
+
+	absPath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open model archive: %w", err)
+	}
+
+	// Bundles with the same name in different directories must not share a cache entry
+	sum := sha256.Sum256([]byte(absPath))
+	archiveKey := filepath.Base(archivePath) + "-" + hex.EncodeToString(sum[:])[:12]
+
+	modelPath := filepath.Join(r.modelsDir(), "archive", archiveKey, filepath.FromSlash(entry))
+	if stat, err := os.Stat(modelPath); err == nil && !stat.ModTime().Before(archiveStat.ModTime()) {
+		r.observeCacheHit(modelPath)
+		r.touchModel(modelPath)
+		return modelPath, nil
+	}
+
+	if err := r.mkdirCache(filepath.Dir(modelPath)); err != nil {
+		return "", err
+	}
+
+	if err := archive.Extract(archivePath, modelPath, entry); err != nil {
+		return "", fmt.Errorf("failed to extract %s from %s: %w", entry, archivePath, err)
+	}
+
+	if err := os.Chmod(modelPath, r.cacheFileMode); err != nil {
+		return "", fmt.Errorf("failed to set model permissions: %w", err)
+	}
+
+	r.touchModel(modelPath)
+	return modelPath, nil
+}
@@ -0,0 +1,49 @@
+package onnx
+
+import "time"
+
+// Observer receives download and cache events from a Runtime, e.g. to record metrics
+// or traces. Methods are called inline, so they should return quickly. Embed
+// NopObserver to implement only the events of interest.
+type Observer interface {
+	// OnDownloadStart is called before a runtime archive, model or release lookup is fetched
+	OnDownloadStart(url string)
+
+	// OnDownloadComplete is called when a download finishes, with a nil err on success
+	OnDownloadComplete(url string, duration time.Duration, err error)
+
+	// OnCacheHit is called when a runtime library or model is served from the cache
+	OnCacheHit(path string)
+}
+
+// NopObserver is an Observer that ignores every event
+type NopObserver struct{}
+
+func (NopObserver) OnDownloadStart(string)                          {}
+func (NopObserver) OnDownloadComplete(string, time.Duration, error) {}
+func (NopObserver) OnCacheHit(string)                               {}
+
+// WithObserver registers an Observer for download and cache events
+func WithObserver(observer Observer) Option {
+	return func(r *Runtime) { r.observer = observer }
+}
+
+// observeDownload runs fn, reporting it to the observer as a download of url
+func (r *Runtime) observeDownload(url string, fn func() error) error {
+	if r.observer == nil {
+		return fn()
+	}
+
+	r.observer.OnDownloadStart(url)
+	start := time.Now()
+	err := fn()
+	r.observer.OnDownloadComplete(url, time.Since(start), err)
+	return err
+}
+
+// observeCacheHit reports a cache hit for path to the observer
+func (r *Runtime) observeCacheHit(path string) {
+	if r.observer != nil {
+		r.observer.OnCacheHit(path)
+	}
+}
@@ -2,11 +2,24 @@ package onnx
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	ort "github.com/yalue/onnxruntime_go"
 
@@ -17,15 +30,72 @@ import (
 const (
 	currentVersion = "1.20.0"
 	defaultBaseURL = "https://github.com/microsoft/onnxruntime/releases/download"
+	maxNestedDepth = 1
+
+	defaultURLTemplate = "{base}/{tag}/onnxruntime-{os}-{arch}{gpu}-{version}{ext}"
+)
+
+var (
+	loadedMu      sync.Mutex
+	loadedLibrary string
+	loadedRefs    int
 )
 
 // Runtime manages ONNX Runtime initialization and configuration
 type Runtime struct {
-	baseURL     string
-	version     string
-	cachePath   string
-	libraryPath string
-	gpu         bool
+	baseURL              string
+	version              string
+	cachePath            string
+	libraryPath          string
+	gpu                  bool
+	deterministic        bool
+	cacheFileMode        os.FileMode
+	fullExtraction       bool
+	insecureTLS          bool
+	embeddedFS           fs.FS
+	embeddedName         string
+	manifestFS           fs.FS
+	manifestName         string
+	nestedExtraction     bool
+	tempDir              string
+	hfToken              string
+	hfRevision           string
+	buildTag             string
+	urlTemplate          string
+	failFastInit         bool
+	extractedLibraryPath string
+	downloadBufferSize   int
+	archiveEntry         string
+	cacheKey             string
+	streamingExtract     bool
+	modelCacheDir        string
+	modelCacheLimit      int64
+	provider             string
+	cudaDevice           int
+	intraOpThreads       int
+	disableCPUArenaOnGPU bool
+	maxRedirects         int
+	client               *http.Client
+	tlsConfig            *tls.Config
+	transportTuning      *TransportTuning
+	clientOnce           sync.Once
+	downloadClient       *http.Client
+	observer             Observer
+	signingKey           ed25519.PublicKey
+	providerLibraries    []string
+	breakerThreshold     int
+	breakerWindow        time.Duration
+	breakerCooldown      time.Duration
+	multipart            int
+	lazyInit             bool
+	initMu               sync.Mutex
+	initialized          bool
+	closed               atomic.Bool
+	healthMu             sync.Mutex
+	healthRunning        bool
+	healthChecked        bool
+	healthErr            error
+	closeOnce            sync.Once
 }
 
 // Option is a functional option for configuring Runtime
@@ -36,53 +106,288 @@ func WithBaseURL(url string) Option {
 	return func(r *Runtime) { r.baseURL = url }
 }
 
-// WithVersion sets the ONNX Runtime version
+// WithVersion sets the ONNX Runtime version. "latest" resolves to the newest
+// release on GitHub when the runtime is ensured, see LatestVersion.
 func WithVersion(version string) Option {
 	return func(r *Runtime) { r.version = version }
 }
 
+// WithBuildTag sets the release tag the runtime is downloaded from, replacing the
+// default "v<version>", e.g. for nightly or pre-release builds
+func WithBuildTag(tag string) Option {
+	return func(r *Runtime) { r.buildTag = tag }
+}
+
+// WithURLTemplate sets the template used to build the runtime download URL for mirrors
+// that don't follow the GitHub release naming. Supported placeholders are {base}, {tag},
+// {version}, {os}, {arch}, {gpu} ("-gpu" or empty) and {ext} (".zip" or ".tgz").
+// The default is "{base}/{tag}/onnxruntime-{os}-{arch}{gpu}-{version}{ext}".
+func WithURLTemplate(tmpl string) Option {
+	return func(r *Runtime) { r.urlTemplate = tmpl }
+}
+
 // WithCachePath sets the cache directory
 func WithCachePath(path string) Option {
 	return func(r *Runtime) { r.cachePath = path }
 }
 
+// WithCacheKey namespaces the runtime cache so runtimes of the same version from
+// different sources don't overwrite each other in a shared cache. It defaults to a
+// key derived from the base URL, URL template and build tag, with official releases
+// left un-namespaced.
+func WithCacheKey(key string) Option {
+	return func(r *Runtime) { r.cacheKey = key }
+}
+
 // WithLibraryPath sets a direct path to the ONNX Runtime library
 func WithLibraryPath(path string) Option {
 	return func(r *Runtime) { r.libraryPath = path }
 }
 
+// WithExtractedLibraryPath downloads and extracts the runtime library to exactly this
+// path instead of the cache. Unlike WithLibraryPath, the library doesn't need to exist yet.
+// With WithFullExtraction, the provider libraries are extracted next to it. The runtime
+// URL is recorded in path+".source", so another version is extracted over it.
+func WithExtractedLibraryPath(path string) Option {
+	return func(r *Runtime) { r.extractedLibraryPath = path }
+}
+
+// WithMultipart downloads the runtime archive as the given number of parts, named
+// <url>.001, <url>.002 and so on, and concatenates them before extraction
+func WithMultipart(parts int) Option {
+	return func(r *Runtime) { r.multipart = parts }
+}
+
+// WithHTTPClient sets the HTTP client used for all runtime and model downloads,
+// e.g. one whose transport dials a Unix socket to reach an egress broker
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Runtime) { r.client = client }
+}
+
+// WithTLSConfig sets the TLS configuration for downloads, e.g. to require TLS 1.2+ or
+// restrict cipher suites. With WithHTTPClient it replaces the TLS config of the client's
+// transport, which must be an *http.Transport; New fails with ErrUnsupportedTransport otherwise.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(r *Runtime) { r.tlsConfig = config }
+}
+
+// TransportTuning adjusts the connection pool of the download transport. Zero fields
+// keep the defaults.
+type TransportTuning struct {
+	MaxIdleConns        int           `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost     int           `json:"max_conns_per_host,omitempty"`
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout,omitempty"`
+	DisableKeepAlives   bool          `json:"disable_keep_alives,omitempty"`
+}
+
+// WithTransportTuning tunes connection reuse for downloads. Like WithTLSConfig it needs the
+// transport of a WithHTTPClient client to be an *http.Transport.
+func WithTransportTuning(tuning TransportTuning) Option {
+	return func(r *Runtime) { r.transportTuning = &tuning }
+}
+
+// WithMaxRedirects limits how many redirects a download may follow, 0 disallows them
+func WithMaxRedirects(n int) Option {
+	return func(r *Runtime) { r.maxRedirects = n }
+}
+
+// WithProviderLibraries limits WithFullExtraction to the execution provider libraries
+// named (e.g. "cuda", "tensorrt"), skipping the rest of the archive's providers to save
+// disk space. The shared provider library is always kept. The whole archive is still
+// downloaded, and an already extracted runtime is not revisited when the selection changes.
+func WithProviderLibraries(providers ...string) Option {
+	return func(r *Runtime) { r.providerLibraries = providers }
+}
+
 // WithGPU enables downloading the GPU version of the ONNX Runtime library
 func WithGPU(enabled bool) Option {
 	return func(r *Runtime) { r.gpu = enabled }
 }
 
-// New creates a new ONNX Runtime manager
+// WithCacheFileMode sets the permissions of files written to the cache.
+// Directories it creates get the same permissions plus execute bits wherever read is
+// set, so 0664 yields group-writable 0775 directories for shared caches. Existing
+// directories keep their permissions.
+func WithCacheFileMode(mode os.FileMode) Option {
+	return func(r *Runtime) { r.cacheFileMode = mode.Perm() }
+}
+
+// WithFullExtraction extracts the archive's entire lib directory into a dedicated
+// cache directory instead of only the main library. This is required for GPU
+// builds, where onnxruntime loads its provider libraries (CUDA, TensorRT) from
+// the directory containing the main library. Those providers still resolve their
+// own dependencies (cuDNN, CUDA runtime) through the OS loader, so those must be
+// on LD_LIBRARY_PATH on Linux or PATH on Windows.
+func WithFullExtraction(enabled bool) Option {
+	return func(r *Runtime) { r.fullExtraction = enabled }
+}
+
+// WithInsecureTLS disables TLS certificate verification for runtime downloads.
+// This is insecure and only intended for internal mirrors with self-signed certificates.
+func WithInsecureTLS(enabled bool) Option {
+	return func(r *Runtime) { r.insecureTLS = enabled }
+}
+
+// WithNestedExtraction searches one level of archives nested inside the downloaded
+// archive when the library isn't found at the top level, for mirrors that double-wrap
+func WithNestedExtraction(enabled bool) Option {
+	return func(r *Runtime) { r.nestedExtraction = enabled }
+}
+
+// WithTempDir sets where archives are downloaded and extracted before the library
+// is moved into the cache, for caches on partitions too small to hold the archive
+func WithTempDir(path string) Option {
+	return func(r *Runtime) { r.tempDir = path }
+}
+
+// WithFailFastInit trusts existing cache entries as-is for faster startup: a cached
+// library is returned without touching cache directory permissions, and a cached
+// archive is extracted without checksum verification. Fresh downloads are still verified.
+// Only use this when the cache can't be tampered with, e.g. a read-only image layer.
+func WithFailFastInit(enabled bool) Option {
+	return func(r *Runtime) { r.failFastInit = enabled }
+}
+
+// WithDownloadBufferSize sets the buffer size used when writing downloads to disk.
+// Larger buffers (e.g. 1MB) reduce syscalls for large downloads on fast links.
+// The default is io.Copy's 32KB.
+func WithDownloadBufferSize(size int) Option {
+	return func(r *Runtime) { r.downloadBufferSize = size }
+}
+
+// WithArchiveEntry sets the full path of the library inside the archive, as returned by
+// ListArchiveContents, instead of matching by file name. Use it for archives that contain
+// several libraries with the same name.
+func WithArchiveEntry(path string) Option {
+	return func(r *Runtime) { r.archiveEntry = path }
+}
+
+// WithStreamingExtract extracts tar.gz runtime archives while they download instead of
+// saving the archive first, halving disk I/O and skipping the temporary archive file.
+// Zip archives, WithNestedExtraction, WithChecksumManifest and WithSignatureVerification
+// always download the archive first, so nothing unverified reaches the cache.
+func WithStreamingExtract(enabled bool) Option {
+	return func(r *Runtime) { r.streamingExtract = enabled }
+}
+
+// WithLazyInit makes New return without downloading or initializing the runtime.
+// That happens on the first NewSessionOptions call, HealthHandler probe or explicit Initialize.
+func WithLazyInit(enabled bool) Option {
+	return func(r *Runtime) { r.lazyInit = enabled }
+}
+
+// WithDeterministic configures sessions created via NewSessionOptions for
+// reproducible execution by pinning intra-op and inter-op threads to 1
+func WithDeterministic(enabled bool) Option {
+	return func(r *Runtime) { r.deterministic = enabled }
+}
+
+// New creates a new ONNX Runtime manager. The runtime is downloaded and initialized
+// before New returns, unless WithLazyInit defers it to first use.
 func New(ctx context.Context, opts ...Option) (*Runtime, error) {
+	runtime, err := newRuntime(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTranslated() {
+		slog.Warn("onnx: running under Rosetta 2, build for darwin/arm64 to use the native runtime")
+	}
+
+	if runtime.lazyInit {
+		return runtime, nil
+	}
+
+	if err := runtime.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	return runtime, nil
+}
+
+// Initialize downloads the runtime if needed and initializes the ONNX Runtime environment.
+// New calls it unless WithLazyInit is set; once it has succeeded further calls do nothing.
+func (r *Runtime) Initialize(ctx context.Context) error {
+	r.initMu.Lock()
+	defer r.initMu.Unlock()
+
+	if r.initialized {
+		return nil
+	}
+	if r.closed.Load() {
+		return errors.New("runtime is closed")
+	}
+
+	libPath, err := r.EnsureRuntime(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ensure runtime: %w", err)
+	}
+
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	// The environment and shared library are process-wide. Runtimes loading the same
+	// library share it, and the last one closed destroys it.
+	if ort.IsInitialized() {
+		if loadedLibrary == "" {
+			return fmt.Errorf("%w: environment was initialized outside this package", ErrRuntimeConflict)
+		}
+		if loadedLibrary != libPath {
+			return fmt.Errorf("%w: %s is loaded, cannot load %s", ErrRuntimeConflict, loadedLibrary, libPath)
+		}
+		loadedRefs++
+		r.initialized = true
+		return nil
+	}
+
+	ort.SetSharedLibraryPath(libPath)
+
+	if err := ort.InitializeEnvironment(); err != nil {
+		// Only reached when no environment existed before, so anything left is this call's
+		if ort.IsInitialized() {
+			ort.DestroyEnvironment()
+		}
+		return fmt.Errorf("%w: %w", ErrInitEnv, err)
+	}
+	loadedLibrary = libPath
+	loadedRefs = 1
+	r.initialized = true
+	return nil
+}
+
+// newRuntime applies options over the defaults without downloading or initializing anything
+func newRuntime(opts ...Option) (*Runtime, error) {
 	defaultCachePath, err := defaultCachePath()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default cache path: %w", err)
 	}
 
 	runtime := &Runtime{
-		baseURL:   defaultBaseURL,
-		version:   currentVersion,
-		cachePath: defaultCachePath,
-		gpu:       false,
+		baseURL:       defaultBaseURL,
+		version:       currentVersion,
+		cachePath:     defaultCachePath,
+		gpu:           false,
+		cacheFileMode: 0644,
+		maxRedirects:  -1,
+	}
+
+	if err := runtime.applyEnv(); err != nil {
+		return nil, err
 	}
 
 	for _, opt := range opts {
 		opt(runtime)
 	}
 
-	libPath, err := runtime.EnsureRuntime(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to ensure runtime: %w", err)
+	if err := runtime.checkProvider(); err != nil {
+		return nil, err
+	}
+	if err := runtime.checkTransport(); err != nil {
+		return nil, err
 	}
 
-	ort.SetSharedLibraryPath(libPath)
-
-	if err := ort.InitializeEnvironment(); err != nil {
-		return nil, fmt.Errorf("failed to initialize environment: %w", err)
+	if runtime.insecureTLS {
+		slog.Warn("onnx: TLS certificate verification is disabled for runtime downloads")
 	}
 	return runtime, nil
 }
@@ -94,11 +399,14 @@ type RuntimeInfo struct {
 	Arch        string
 	GPU         bool
 	LibraryName string
+	// Translated is true when an amd64 process runs under Rosetta 2 on Apple Silicon,
+	// where the x86_64 library is emulated and significantly slower than a native arm64 build
+	Translated bool
 }
 
 // GetRuntimeInfo returns information about the current runtime
 func (r *Runtime) RuntimeInfo() *RuntimeInfo {
-	info := &RuntimeInfo{Version: r.version, GPU: r.gpu}
+	info := &RuntimeInfo{Version: r.version, GPU: r.gpu, Translated: isTranslated()}
 
 	switch runtime.GOOS {
 	case "windows":
@@ -107,9 +415,13 @@ func (r *Runtime) RuntimeInfo() *RuntimeInfo {
 	case "darwin":
 		info.OS = "osx"
 		info.LibraryName = fmt.Sprintf("libonnxruntime.%s.dylib", info.Version)
-	default:
+	case "linux":
 		info.OS = "linux"
 		info.LibraryName = fmt.Sprintf("libonnxruntime.so.%s", info.Version)
+	default:
+		// Upstream only builds for the above, so OS and Arch are left empty
+		info.LibraryName = fmt.Sprintf("libonnxruntime.so.%s", info.Version)
+		return info
 	}
 
 	switch runtime.GOARCH {
@@ -131,35 +443,85 @@ func (r *Runtime) RuntimeInfo() *RuntimeInfo {
 		if info.OS == "win" {
 			info.Arch = "x86"
 		}
+	case "arm":
+		// Upstream does not publish 32-bit ARM builds, so Arch is left empty
 	}
 	return info
 }
 
 // RuntimeURL returns the download URL for a specific runtime
 func (r *Runtime) RuntimeURL(info *RuntimeInfo) string {
-	base := fmt.Sprintf("%s/v%s/", r.baseURL, info.Version)
+	gpu := ""
+	if info.GPU && (info.OS == "linux" || info.OS == "win") && info.Arch == "x64" {
+		gpu = "-gpu"
+	}
+	return r.renderURL(info, info.Arch, gpu)
+}
 
-	name := fmt.Sprintf("onnxruntime-%s-%s", info.OS, info.Arch)
+// runtimeURLs returns RuntimeURL followed by the asset names earlier and later releases
+// used for the same platform, to try in order when a URL is not found
+func (r *Runtime) runtimeURLs(info *RuntimeInfo) []string {
+	primary := r.RuntimeURL(info)
+	urls := []string{primary}
 
-	if info.GPU && (info.OS == "linux" || info.OS == "win") && info.Arch == "x64" {
-		name += "-gpu"
+	add := func(arch, gpu string) {
+		url := r.renderURL(info, arch, gpu)
+		if !slices.Contains(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+
+	if info.OS == "osx" {
+		if info.Arch == "x86_64" {
+			add("x64", "")
+		}
+		add("universal2", "")
+	}
+	if strings.Contains(primary, "-gpu") {
+		add(info.Arch, "-gpu-cuda12")
+		add(info.Arch, "-gpu-cuda11")
+	}
+	return urls
+}
+
+// renderURL fills the URL template for the given architecture and GPU suffix
+func (r *Runtime) renderURL(info *RuntimeInfo, arch, gpu string) string {
+	tmpl := r.urlTemplate
+	if tmpl == "" {
+		tmpl = defaultURLTemplate
+	}
+
+	tag := r.buildTag
+	if tag == "" {
+		tag = "v" + info.Version
 	}
 
-	name += fmt.Sprintf("-%s", info.Version)
+	ext := ".tgz"
 	if info.OS == "win" {
-		name += ".zip"
-	} else {
-		name += ".tgz"
+		ext = ".zip"
 	}
-	return base + name
+
+	return strings.NewReplacer(
+		"{base}", r.baseURL,
+		"{tag}", tag,
+		"{version}", info.Version,
+		"{os}", info.OS,
+		"{arch}", arch,
+		"{gpu}", gpu,
+		"{ext}", ext,
+	).Replace(tmpl)
 }
 
 // EnsureRuntime downloads and extracts the ONNX Runtime library
 func (r *Runtime) EnsureRuntime(ctx context.Context) (string, error) {
-	runtime := r.RuntimeInfo()
+	if err := r.resolveVersion(ctx); err != nil {
+		return "", err
+	}
+
+	info := r.RuntimeInfo()
 
 	if r.libraryPath != "" {
-		if filepath.Ext(r.libraryPath) != filepath.Ext(runtime.LibraryName) {
+		if !isSharedLibrary(r.libraryPath, info.OS) {
 			return "", fmt.Errorf("specified library invalid for current platform")
 		}
 		if _, err := os.Stat(r.libraryPath); err != nil {
@@ -168,54 +530,592 @@ func (r *Runtime) EnsureRuntime(ctx context.Context) (string, error) {
 		return r.libraryPath, nil
 	}
 
-	libDir := filepath.Join(r.cachePath, "runtime")
-	if err := os.MkdirAll(libDir, 0755); err != nil {
+	if r.embeddedFS != nil {
+		return r.materializeEmbedded()
+	}
+
+	if info.Arch == "" {
+		return "", r.unsupportedPlatform()
+	}
+
+	_, libPath, targetPath := r.runtimePaths(info)
+	source := r.extractedSource(info)
+
+	if r.failFastInit {
+		if cached, err := r.checkCachedLibrary(libPath, source); err != nil {
+			return "", err
+		} else if cached {
+			r.observeCacheHit(libPath)
+			return libPath, nil
+		}
+	}
+
+	libDir := filepath.Dir(libPath)
+	if r.tempDir != "" {
+		if err := os.MkdirAll(r.tempDir, 0755); err != nil {
+			return "", err
+		}
+	} else if err := r.mkdirCache(filepath.Dir(targetPath)); err != nil {
+		return "", err
+	}
+	if r.extractedLibraryPath != "" {
+		if err := os.MkdirAll(libDir, 0755); err != nil {
+			return "", err
+		}
+	} else if err := r.mkdirCache(libDir); err != nil {
 		return "", err
 	}
 
-	libPath := filepath.Join(libDir, runtime.LibraryName)
-	if _, err := os.Stat(libPath); err == nil {
+	if cached, err := r.checkCachedLibrary(libPath, source); err != nil {
+		return "", err
+	} else if cached {
+		r.observeCacheHit(libPath)
 		return libPath, nil
 	}
 
-	url := r.RuntimeURL(runtime)
+	// Other processes sharing the cache wait here instead of downloading the same archive
+	unlock, err := acquireLock(ctx, libPath+".lock")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
 
-	targetPath := filepath.Join(r.cachePath, "runtime", filepath.Base(url))
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+	if cached, err := r.checkCachedLibrary(libPath, source); err != nil {
 		return "", err
+	} else if cached {
+		r.observeCacheHit(libPath)
+		return libPath, nil
 	}
 
-	if _, err := os.Stat(targetPath); err != nil {
-		targetPath, err = download.DownloadFile(ctx, url, targetPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to download runtime: %w", err)
+	_, statErr := os.Stat(targetPath)
+	downloaded := statErr != nil
+	// Verified archives must be checked before anything is extracted into the cache
+	streaming := r.streamingExtract && downloaded && !r.nestedExtraction && r.multipart == 0 &&
+		r.manifestFS == nil && r.signingKey == nil && canStream(targetPath)
+
+	if !streaming {
+		if err := r.fetchArchive(ctx, r.runtimeURLs(info), targetPath); err != nil {
+			return "", err
 		}
 	}
 
-	if strings.HasSuffix(targetPath, ".zip") {
-		if err := archive.ExtractFromZip(targetPath, libPath, runtime.LibraryName); err != nil {
-			return "", fmt.Errorf("failed to extract runtime: %w", err)
+	// On failure, remove anything this call created so a retry starts clean. An archive
+	// that failed to extract for lack of disk space is kept so the retry can skip the download.
+	ok := false
+	keepArchive := false
+	defer func() {
+		if ok {
+			return
 		}
+		if downloaded && !keepArchive {
+			os.Remove(targetPath)
+		}
+		os.Remove(libPath)
+	}()
+
+	extractDir := libDir
+	if r.tempDir != "" {
+		stageDir, err := os.MkdirTemp(r.tempDir, "extract-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		defer os.RemoveAll(stageDir)
+		extractDir = stageDir
+	}
+
+	if streaming {
+		err = tryURLs(r.runtimeURLs(info), func(url string) error {
+			return r.observeDownload(url, func() error {
+				return r.streamExtract(ctx, url, extractDir, info, libPath)
+			})
+		})
 	} else {
-		if err := archive.ExtractFromTarGz(targetPath, libPath, runtime.LibraryName); err != nil {
-			return "", fmt.Errorf("failed to extract runtime: %w", err)
+		depth := 0
+		if r.nestedExtraction {
+			depth = maxNestedDepth
 		}
+
+		err = r.extractFiles(targetPath, extractDir, info, libPath,
+			func(destDir string) error { return r.extractLibDir(targetPath, destDir) },
+			func(destPath, entry string) error { return archive.ExtractNested(targetPath, destPath, entry, depth) },
+		)
 	}
 
-	if err := os.Remove(targetPath); err != nil {
-		return "", fmt.Errorf("failed to remove archive: %w", err)
+	if err == nil && extractDir != libDir {
+		if err = moveDir(extractDir, libDir); err != nil {
+			err = fmt.Errorf("failed to move runtime into cache: %w", err)
+		}
+	}
+
+	if isDiskFull(err) {
+		keepArchive = true
+		return "", fmt.Errorf("%w: %w", ErrInsufficientDiskSpace, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(libPath); err != nil {
+		return "", fmt.Errorf("%w: file %s %w", ErrExtract, info.LibraryName, ErrArchiveEntryNotFound)
+	}
+
+	ok = true
+
+	// Without the record the library is just extracted again next time
+	if source != "" {
+		if err := r.writeCacheFile(libPath+".source", strings.NewReader(source)); err != nil {
+			slog.Warn("onnx: failed to record extracted library source", "path", libPath, "error", err)
+		}
+	}
+
+	// A leftover archive is harmless, so transient locks must not fail the init
+	if !streaming {
+		if err := os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
+			slog.Warn("onnx: failed to remove runtime archive", "path", targetPath, "error", err)
+		}
 	}
 	return libPath, nil
 }
 
+// checkCachedLibrary reports whether a usable library is cached at libPath. A library
+// that can't be read, e.g. after a container image COPY dropped its permissions, gets
+// its mode reset to the cache file mode, or is removed so it's extracted again. When
+// source is set, the library must have been extracted from it.
+func (r *Runtime) checkCachedLibrary(libPath, source string) (bool, error) {
+	if _, err := os.Stat(libPath); err != nil {
+		return false, nil
+	}
+	if source != "" {
+		if recorded, err := os.ReadFile(libPath + ".source"); err != nil || string(recorded) != source {
+			return false, nil
+		}
+	}
+	if isReadable(libPath) {
+		return true, nil
+	}
+
+	if err := os.Chmod(libPath, r.cacheFileMode); err == nil && isReadable(libPath) {
+		slog.Warn("onnx: reset permissions of cached library", "path", libPath, "mode", r.cacheFileMode)
+		return true, nil
+	}
+
+	if err := os.Remove(libPath); err != nil {
+		return false, fmt.Errorf("cached library %s is not readable and could not be replaced: %w", libPath, err)
+	}
+	slog.Warn("onnx: cached library is not readable, extracting it again", "path", libPath)
+	return false, nil
+}
+
+// isReadable reports whether path can be opened for reading
+func isReadable(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// fetchArchive downloads the runtime archive from the first of urls found, unless it is
+// already present, and verifies it
+func (r *Runtime) fetchArchive(ctx context.Context, urls []string, archivePath string) error {
+	// An archive left by an earlier call may have come from any of urls
+	candidates := urls
+	if _, err := os.Stat(archivePath); err != nil {
+		var sourceURL string
+		err := tryURLs(urls, func(url string) error {
+			sourceURL = url
+			return r.observeDownload(url, func() error {
+				_, err := download.DownloadParts(ctx, r.archiveURLs(url), archivePath, r.runtimeDownloadOptions()...)
+				return err
+			})
+		})
+		if isDiskFull(err) {
+			return fmt.Errorf("%w: %w", ErrInsufficientDiskSpace, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDownload, err)
+		}
+		if err := os.Chmod(archivePath, r.cacheFileMode); err != nil {
+			return fmt.Errorf("failed to set archive permissions: %w", err)
+		}
+		candidates = []string{sourceURL}
+	} else if r.failFastInit {
+		return nil
+	}
+
+	names := make([]string, len(candidates))
+	for i, url := range candidates {
+		names[i] = filepath.Base(url)
+	}
+	if err := r.verifyArchive(archivePath, names); err != nil {
+		os.Remove(archivePath)
+		return err
+	}
+	if err := r.verifySignature(ctx, candidates, archivePath); err != nil {
+		os.Remove(archivePath)
+		return err
+	}
+	return nil
+}
+
+// tryURLs calls fn with each URL in turn while the previous one was not found upstream.
+// When none is found the error lists every URL attempted.
+func tryURLs(urls []string, fn func(url string) error) error {
+	var errs []error
+	for _, url := range urls {
+		err := fn(url)
+		if !errors.Is(err, download.ErrNotFound) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", url, err))
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("no release asset found, tried %d URLs: %w", len(errs), errors.Join(errs...))
+}
+
+// archiveURLs returns the URLs the runtime archive is downloaded from, one per part
+func (r *Runtime) archiveURLs(url string) []string {
+	if r.multipart <= 0 {
+		return []string{url}
+	}
+
+	urls := make([]string, r.multipart)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s.%03d", url, i+1)
+	}
+	return urls
+}
+
+// defaultClient is used for downloads when no WithHTTPClient client is set. It keeps more
+// idle connections per host than http.DefaultClient so the runtime archive, its parts and
+// models downloaded from the same CDN reuse connections instead of repeating TLS handshakes.
+var defaultClient = &http.Client{Transport: newDefaultTransport()}
+
+func newDefaultTransport() http.RoundTripper {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+	transport = transport.Clone()
+	transport.MaxIdleConnsPerHost = 8
+	return transport
+}
+
+// httpClient returns the client used for every download made by the Runtime. It is built
+// once so a transport cloned for TLS or tuning settings keeps its idle connections.
+func (r *Runtime) httpClient() *http.Client {
+	r.clientOnce.Do(func() { r.downloadClient = r.newHTTPClient() })
+	return r.downloadClient
+}
+
+// checkTransport fails when WithTLSConfig or WithTransportTuning can't be applied because
+// the client's transport isn't an *http.Transport, rather than downloading without them
+func (r *Runtime) checkTransport() error {
+	if r.tlsConfig == nil && r.transportTuning == nil {
+		return nil
+	}
+
+	base := r.client
+	if base == nil {
+		base = defaultClient
+	}
+	transport := base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if _, ok := transport.(*http.Transport); !ok {
+		return fmt.Errorf("%w: TLS and transport settings need an *http.Transport, the client uses %T", ErrUnsupportedTransport, transport)
+	}
+	return nil
+}
+
+// newHTTPClient applies the Runtime's TLS, transport and redirect settings to the base client
+func (r *Runtime) newHTTPClient() *http.Client {
+	base := r.client
+	if base == nil {
+		base = defaultClient
+	}
+	if !r.insecureTLS && r.tlsConfig == nil && r.transportTuning == nil && r.maxRedirects < 0 {
+		return base
+	}
+
+	client := *base
+	if r.insecureTLS || r.tlsConfig != nil || r.transportTuning != nil {
+		transport, ok := client.Transport.(*http.Transport)
+		if client.Transport == nil {
+			transport, ok = http.DefaultTransport.(*http.Transport)
+		}
+		if ok {
+			transport = transport.Clone()
+			if r.tlsConfig != nil {
+				transport.TLSClientConfig = r.tlsConfig.Clone()
+			}
+			if r.insecureTLS {
+				if transport.TLSClientConfig == nil {
+					transport.TLSClientConfig = &tls.Config{}
+				}
+				transport.TLSClientConfig.InsecureSkipVerify = true
+			}
+			if r.transportTuning != nil {
+				r.transportTuning.apply(transport)
+			}
+			client.Transport = transport
+		}
+	}
+	if r.maxRedirects >= 0 {
+		limit := r.maxRedirects
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > limit {
+				return fmt.Errorf("stopped after %d redirects", limit)
+			}
+			return nil
+		}
+	}
+	return &client
+}
+
+// downloadOptions returns the options shared by every download made by the Runtime
+func (r *Runtime) downloadOptions() []download.Option {
+	return []download.Option{
+		download.WithClient(r.httpClient()),
+		download.WithBufferSize(r.downloadBufferSize),
+	}
+}
+
+// apply sets the non-zero fields of t on transport
+func (t *TransportTuning) apply(transport *http.Transport) {
+	if t.MaxIdleConns > 0 {
+		transport.MaxIdleConns = t.MaxIdleConns
+	}
+	if t.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = t.MaxIdleConnsPerHost
+	}
+	if t.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = t.MaxConnsPerHost
+	}
+	if t.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = t.IdleConnTimeout
+	}
+	if t.DisableKeepAlives {
+		transport.DisableKeepAlives = true
+	}
+}
+
+// runtimePaths returns the download URL, cached library path and archive path for a runtime
+func (r *Runtime) runtimePaths(info *RuntimeInfo) (url, libPath, archivePath string) {
+	url = r.RuntimeURL(info)
+
+	runtimeDir := filepath.Join(r.cachePath, "runtime", r.cacheNamespace())
+
+	libDir := runtimeDir
+	if r.fullExtraction {
+		libDir = filepath.Join(runtimeDir, strings.TrimSuffix(strings.TrimSuffix(filepath.Base(url), ".zip"), ".tgz"))
+	}
+	libPath = filepath.Join(libDir, info.LibraryName)
+	if r.extractedLibraryPath != "" {
+		libPath = r.extractedLibraryPath
+	}
+
+	archiveDir := runtimeDir
+	if r.tempDir != "" {
+		archiveDir = r.tempDir
+	}
+	return url, libPath, filepath.Join(archiveDir, filepath.Base(url))
+}
+
+// extractedSource returns the runtime URL recorded next to a library extracted with
+// WithExtractedLibraryPath. That path doesn't change with the version, unlike cache paths.
+func (r *Runtime) extractedSource(info *RuntimeInfo) string {
+	if r.extractedLibraryPath == "" {
+		return ""
+	}
+	return r.RuntimeURL(info)
+}
+
+// cacheNamespace returns the runtime cache subdirectory for the configured cache key.
+// Official releases aren't namespaced, so existing caches stay valid.
+func (r *Runtime) cacheNamespace() string {
+	if r.cacheKey != "" {
+		return r.cacheKey
+	}
+
+	source := r.baseURL
+	if (r.urlTemplate != "" && r.urlTemplate != defaultURLTemplate) || r.buildTag != "" {
+		source += "\x00" + r.urlTemplate + "\x00" + r.buildTag
+	} else if r.baseURL == defaultBaseURL {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// extractFiles extracts the library, or with full extraction the whole lib directory,
+// into extractDir and applies the cache file mode. source names the archive in errors.
+func (r *Runtime) extractFiles(source, extractDir string, info *RuntimeInfo, libPath string, extractDirFn func(destDir string) error, extractFileFn func(destPath, entry string) error) error {
+	if r.fullExtraction {
+		if err := extractDirFn(extractDir); err != nil {
+			return fmt.Errorf("%w from %s: %w", ErrExtract, source, err)
+		}
+
+		entries, err := os.ReadDir(extractDir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			if err := os.Chmod(filepath.Join(extractDir, entry.Name()), r.cacheFileMode); err != nil {
+				return fmt.Errorf("failed to set runtime permissions: %w", err)
+			}
+		}
+
+		if name := filepath.Base(libPath); name != info.LibraryName {
+			if err := os.Rename(filepath.Join(extractDir, info.LibraryName), filepath.Join(extractDir, name)); err != nil {
+				return fmt.Errorf("%w from %s: %w", ErrExtract, source, err)
+			}
+		}
+		return nil
+	}
+
+	entry := info.LibraryName
+	if r.archiveEntry != "" {
+		entry = r.archiveEntry
+	}
+
+	extractPath := filepath.Join(extractDir, filepath.Base(libPath))
+	if err := extractFileFn(extractPath, entry); err != nil {
+		return fmt.Errorf("%w from %s: %w", ErrExtract, source, err)
+	}
+
+	if err := os.Chmod(extractPath, r.cacheFileMode); err != nil {
+		return fmt.Errorf("failed to set runtime permissions: %w", err)
+	}
+	return nil
+}
+
+// extractLibDir extracts the archive's lib directory into destDir
+func (r *Runtime) extractLibDir(archivePath, destDir string) error {
+	if strings.HasSuffix(archivePath, ".zip") {
+		return archive.ExtractDirFromZip(archivePath, destDir, "lib", r.skipLibrary)
+	}
+	return archive.ExtractDirFromTarGz(archivePath, destDir, "lib", r.skipLibrary)
+}
+
+// providerLibraryPattern matches execution provider libraries, capturing the provider name
+var providerLibraryPattern = regexp.MustCompile(`onnxruntime_providers_([a-z0-9]+)`)
+
+// skipLibrary reports whether a file in the archive's lib directory belongs to a
+// provider excluded by WithProviderLibraries
+func (r *Runtime) skipLibrary(name string) bool {
+	if r.providerLibraries == nil {
+		return false
+	}
+
+	match := providerLibraryPattern.FindStringSubmatch(name)
+	if match == nil || match[1] == "shared" {
+		return false
+	}
+	return !slices.Contains(r.providerLibraries, match[1])
+}
+
+// sharedLibraryPattern matches Linux shared library names, which may carry a version
+// suffix after ".so" (libonnxruntime.so, libonnxruntime.so.1, libonnxruntime.so.1.20.0)
+var sharedLibraryPattern = regexp.MustCompile(`\.so(\.\d+)*$`)
+
+// isSharedLibrary reports whether path is named like a shared library for the given runtime OS
+func isSharedLibrary(path, os string) bool {
+	name := filepath.Base(path)
+
+	switch os {
+	case "win":
+		return strings.EqualFold(filepath.Ext(name), ".dll")
+	case "osx":
+		return filepath.Ext(name) == ".dylib"
+	default:
+		return sharedLibraryPattern.MatchString(name)
+	}
+}
+
+// mkdirCache creates a cache directory and any missing parents with permissions derived
+// from the cache file mode, regardless of the umask. Directories that already exist are
+// left alone, since they may belong to another user of a shared cache.
+func (r *Runtime) mkdirCache(path string) error {
+	mode := r.cacheFileMode | (r.cacheFileMode&0444)>>2
+
+	var missing []string
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	for _, dir := range slices.Backward(missing) {
+		if err := os.Mkdir(dir, mode); err != nil {
+			// Created concurrently by another process, which owns its permissions
+			if errors.Is(err, fs.ErrExist) {
+				continue
+			}
+			return err
+		}
+		if err := os.Chmod(dir, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsupportedPlatform returns an error describing why no runtime build is available
+func (r *Runtime) unsupportedPlatform() error {
+	if runtime.GOARCH == "arm" {
+		return fmt.Errorf("%w: arm32 is not supported by upstream for version %s", ErrUnsupportedPlatform, r.version)
+	}
+	return fmt.Errorf("%w: no upstream build of version %s for %s/%s", ErrUnsupportedPlatform, r.version, runtime.GOOS, runtime.GOARCH)
+}
+
 // Version returns the current ONNX Runtime version
 func (r *Runtime) Version() string {
 	return ort.GetVersion()
 }
 
-// Close cleans up ONNX Runtime resources
+// Close cleans up ONNX Runtime resources. Runtimes sharing a library keep the environment
+// until the last of them is closed. It is safe to call more than once; calls after the
+// first return nil.
 func (r *Runtime) Close() error {
-	return ort.DestroyEnvironment()
+	var err error
+	r.closeOnce.Do(func() {
+		r.initMu.Lock()
+		defer r.initMu.Unlock()
+
+		r.closed.Store(true)
+
+		// A lazy runtime that was never used has nothing to clean up
+		if !r.initialized {
+			return
+		}
+
+		loadedMu.Lock()
+		defer loadedMu.Unlock()
+
+		if loadedRefs--; loadedRefs > 0 {
+			return
+		}
+		if err = ort.DestroyEnvironment(); err != nil {
+			return
+		}
+		loadedLibrary = ""
+	})
+	return err
 }
 
 func defaultCachePath() (string, error) {
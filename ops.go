@@ -0,0 +1,52 @@
+package onnx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/joeychilson/onnx/internal/model"
+)
+
+// ValidateModelOps returns ErrDisallowedOp if the model uses any operator not in allow.
+// Operators in the default ONNX domain are named by op type (e.g. "Conv"), others
+// are qualified by their domain (e.g. "com.microsoft:FusedConv").
+// Nodes in subgraphs and model-local functions are checked too.
+func ValidateModelOps(modelPath string, allow []string) error {
+	return checkModelOps(modelPath, func(op string) bool { return !slices.Contains(allow, op) })
+}
+
+// RejectModelOps returns ErrDisallowedOp if the model uses any operator in deny,
+// named as in ValidateModelOps
+func RejectModelOps(modelPath string, deny []string) error {
+	return checkModelOps(modelPath, func(op string) bool { return slices.Contains(deny, op) })
+}
+
+func checkModelOps(modelPath string, disallowed func(op string) bool) error {
+	m, err := model.Load(modelPath)
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+
+	var rejected []string
+	for _, node := range m.Nodes {
+		op := opName(node)
+		if disallowed(op) && !slices.Contains(rejected, op) {
+			rejected = append(rejected, op)
+		}
+	}
+
+	if len(rejected) > 0 {
+		slices.Sort(rejected)
+		return fmt.Errorf("%w: %s", ErrDisallowedOp, strings.Join(rejected, ", "))
+	}
+	return nil
+}
+
+// opName returns the operator name used by the allow and deny lists
+func opName(node model.Node) string {
+	if node.Domain == "" || node.Domain == "ai.onnx" {
+		return node.OpType
+	}
+	return node.Domain + ":" + node.OpType
+}
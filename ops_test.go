@@ -0,0 +1,53 @@
+package onnx_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joeychilson/onnx"
+)
+
+func TestValidateModelOps(t *testing.T) {
+	modelPath := filepath.Join("testdata", "identity.onnx")
+
+	if err := onnx.ValidateModelOps(modelPath, []string{"Identity"}); err != nil {
+		t.Fatalf("ValidateModelOps with Identity allowed: %v", err)
+	}
+	if err := onnx.ValidateModelOps(modelPath, []string{"Conv"}); !errors.Is(err, onnx.ErrDisallowedOp) {
+		t.Fatalf("expected ErrDisallowedOp, got %v", err)
+	}
+	if err := onnx.RejectModelOps(modelPath, []string{"Identity"}); !errors.Is(err, onnx.ErrDisallowedOp) {
+		t.Fatalf("expected ErrDisallowedOp, got %v", err)
+	}
+	if err := onnx.RejectModelOps(modelPath, []string{"Conv"}); err != nil {
+		t.Fatalf("RejectModelOps with Conv denied: %v", err)
+	}
+}
+
+func TestValidateModelOpsInvalidModel(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "empty.onnx")
+	if err := os.WriteFile(modelPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty file has no graph, so it must not pass as a model without operators
+	if err := onnx.ValidateModelOps(modelPath, nil); err == nil {
+		t.Fatal("expected an error for a model without a graph")
+	}
+}
+
+func TestGetModelStats(t *testing.T) {
+	stats, err := onnx.GetModelStats(filepath.Join("testdata", "identity.onnx"))
+	if err != nil {
+		t.Fatalf("GetModelStats: %v", err)
+	}
+
+	if stats.Nodes != 1 || stats.OpCounts["Identity"] != 1 || len(stats.OpCounts) != 1 {
+		t.Fatalf("unexpected nodes: %d, %v", stats.Nodes, stats.OpCounts)
+	}
+	if stats.Parameters != 0 || stats.ParameterBytes != 0 || stats.FLOPs != 0 {
+		t.Fatalf("unexpected parameters: %+v", stats)
+	}
+}
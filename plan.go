@@ -0,0 +1,95 @@
+package onnx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/joeychilson/onnx/internal/download"
+)
+
+// EnsurePlan describes what EnsureRuntime would do without doing it
+type EnsurePlan struct {
+	// LibraryPath is the path the runtime library is, or would be, loaded from
+	LibraryPath string
+	// Cached reports whether the library is already present and no download is needed
+	Cached bool
+	// URL is the archive that would be downloaded, empty when WithLibraryPath is used
+	URL string
+	// ArchivePath is where the archive would be downloaded to
+	ArchivePath string
+	// ArchiveCached reports whether the archive is already downloaded and only needs extraction
+	ArchiveCached bool
+	// Size is the archive size reported by the server, or -1 if unknown or not fetched
+	Size int64
+}
+
+// Plan reports what EnsureRuntime would do without downloading or extracting anything.
+// When a download is needed, a HEAD request is made for each part of the archive to
+// determine its size, trying fallback URLs in turn while the archive is not found.
+// A "latest" version is resolved through the GitHub API first unless already cached.
+func (r *Runtime) Plan(ctx context.Context) (*EnsurePlan, error) {
+	if err := r.resolveVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	info := r.RuntimeInfo()
+
+	if r.libraryPath != "" {
+		_, err := os.Stat(r.libraryPath)
+		return &EnsurePlan{LibraryPath: r.libraryPath, Cached: err == nil, Size: -1}, nil
+	}
+
+	if r.embeddedFS != nil {
+		libPath := filepath.Join(r.cachePath, "embedded", path.Base(r.embeddedName))
+		_, err := os.Stat(libPath)
+		return &EnsurePlan{LibraryPath: libPath, Cached: err == nil, Size: -1}, nil
+	}
+
+	if info.Arch == "" {
+		return nil, r.unsupportedPlatform()
+	}
+
+	url, libPath, archivePath := r.runtimePaths(info)
+
+	plan := &EnsurePlan{
+		LibraryPath: libPath,
+		URL:         url,
+		ArchivePath: archivePath,
+		Size:        -1,
+	}
+
+	if _, err := os.Stat(libPath); err == nil {
+		plan.Cached = true
+		return plan, nil
+	}
+
+	if _, err := os.Stat(archivePath); err == nil {
+		plan.ArchiveCached = true
+		return plan, nil
+	}
+
+	err := tryURLs(r.runtimeURLs(info), func(url string) error {
+		var total int64
+		for _, partURL := range r.archiveURLs(url) {
+			size, err := download.ContentLength(ctx, partURL, r.runtimeDownloadOptions()...)
+			if err != nil {
+				return err
+			}
+			if size < 0 {
+				total = -1
+				break
+			}
+			total += size
+		}
+		plan.URL = url
+		plan.Size = total
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownload, err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,119 @@
+package onnx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joeychilson/onnx/internal/download"
+)
+
+const (
+	// releasesAPIURL is the GitHub API endpoint for ONNX Runtime releases
+	releasesAPIURL = "https://api.github.com/repos/microsoft/onnxruntime/releases"
+
+	// latestVersionTTL is how long a resolved latest version is reused before asking GitHub again
+	latestVersionTTL = 24 * time.Hour
+)
+
+// LatestVersion returns the version of the newest stable ONNX Runtime release on GitHub.
+// The result is cached on disk for a day to stay clear of the API's rate limits.
+func LatestVersion(ctx context.Context, opts ...Option) (string, error) {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return "", err
+	}
+	return r.latestVersion(ctx)
+}
+
+// resolveVersion replaces the "latest" version with the newest release
+func (r *Runtime) resolveVersion(ctx context.Context) error {
+	if r.version != "latest" {
+		return nil
+	}
+
+	version, err := r.latestVersion(ctx)
+	if err != nil {
+		return err
+	}
+	r.version = version
+	return nil
+}
+
+func (r *Runtime) latestVersion(ctx context.Context) (string, error) {
+	cacheFile := filepath.Join(r.cachePath, "runtime", "latest_version")
+
+	cached, _ := os.ReadFile(cacheFile)
+	if stat, err := os.Stat(cacheFile); err == nil && len(cached) > 0 && time.Since(stat.ModTime()) < latestVersionTTL {
+		return string(cached), nil
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := r.getReleaseJSON(ctx, releasesAPIURL+"/latest", &release); err != nil {
+		if len(cached) > 0 {
+			slog.Warn("onnx: failed to check latest release, using cached version", "version", string(cached), "error", err)
+			return string(cached), nil
+		}
+		return "", fmt.Errorf("%w: %w", ErrDownload, err)
+	}
+
+	version := strings.TrimPrefix(release.TagName, "v")
+	if version == "" {
+		return "", fmt.Errorf("%w: latest release has no tag", ErrDownload)
+	}
+
+	if err := r.mkdirCache(filepath.Dir(cacheFile)); err == nil {
+		if err := os.WriteFile(cacheFile, []byte(version), r.cacheFileMode); err != nil {
+			slog.Warn("onnx: failed to cache latest version", "path", cacheFile, "error", err)
+		}
+	}
+	return version, nil
+}
+
+// getReleaseJSON fetches a GitHub API URL and decodes the JSON response into v
+func (r *Runtime) getReleaseJSON(ctx context.Context, url string, v any) error {
+	opts := append(r.downloadOptions(), download.WithHeader("Accept", "application/vnd.github+json"))
+	return r.observeDownload(url, func() error {
+		return download.Stream(ctx, url, func(body io.Reader) error {
+			return json.NewDecoder(body).Decode(v)
+		}, opts...)
+	})
+}
+
+// ListReleaseAssets returns the names of the files published with an ONNX Runtime
+// release on GitHub, e.g. to find the build matching a platform or provider.
+// The version may be "latest".
+func ListReleaseAssets(ctx context.Context, version string, opts ...Option) ([]string, error) {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	url := releasesAPIURL + "/tags/v" + strings.TrimPrefix(version, "v")
+	if version == "latest" {
+		url = releasesAPIURL + "/latest"
+	}
+
+	var release struct {
+		Assets []struct {
+			Name string `json:"name"`
+		} `json:"assets"`
+	}
+	if err := r.getReleaseJSON(ctx, url, &release); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownload, err)
+	}
+
+	names := make([]string, len(release.Assets))
+	for i, asset := range release.Assets {
+		names[i] = asset.Name
+	}
+	return names, nil
+}
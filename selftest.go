@@ -0,0 +1,46 @@
+package onnx
+
+import (
+	"fmt"
+	"slices"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// SelfTest runs TestModel with session options from the Runtime and checks its output,
+// confirming the library loads models and the execution provider works end to end
+func (r *Runtime) SelfTest() error {
+	options, err := r.NewSessionOptions()
+	if err != nil {
+		return err
+	}
+	defer options.Destroy()
+
+	data := []float32{1, 2, 3, 4}
+	input, err := ort.NewTensor(ort.NewShape(1, 4), data)
+	if err != nil {
+		return fmt.Errorf("self-test: failed to create input: %w", err)
+	}
+	defer input.Destroy()
+
+	output, err := ort.NewEmptyTensor[float32](ort.NewShape(1, 4))
+	if err != nil {
+		return fmt.Errorf("self-test: failed to create output: %w", err)
+	}
+	defer output.Destroy()
+
+	session, err := ort.NewAdvancedSessionWithONNXData(testModel, []string{"x"}, []string{"y"},
+		[]ort.Value{input}, []ort.Value{output}, options)
+	if err != nil {
+		return fmt.Errorf("self-test: failed to create session: %w", err)
+	}
+	defer session.Destroy()
+
+	if err := session.Run(); err != nil {
+		return fmt.Errorf("self-test: failed to run: %w", err)
+	}
+	if got := output.GetData(); !slices.Equal(got, data) {
+		return fmt.Errorf("self-test: unexpected output %v, expected %v", got, data)
+	}
+	return nil
+}
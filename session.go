@@ -0,0 +1,122 @@
+package onnx
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// WithExecutionProvider selects the execution provider appended to session options.
+// "cuda" also enables the GPU runtime download and full extraction, since the CUDA
+// provider library ships next to the main one; "cpu" uses the default provider and
+// the CPU runtime. The name is case-insensitive and New fails for any other provider.
+func WithExecutionProvider(name string) Option {
+	name = strings.ToLower(name)
+	return func(r *Runtime) {
+		r.provider = name
+		switch name {
+		case "cuda":
+			r.gpu = true
+			r.fullExtraction = true
+		case "cpu":
+			r.gpu = false
+		}
+	}
+}
+
+// checkProvider returns an error if WithExecutionProvider named an unsupported provider
+func (r *Runtime) checkProvider() error {
+	switch r.provider {
+	case "", "cpu", "cuda":
+		return nil
+	}
+	return fmt.Errorf("invalid execution provider %q: must be cpu or cuda", r.provider)
+}
+
+// WithCUDADevice sets the CUDA device ID used by the cuda execution provider
+func WithCUDADevice(id int) Option {
+	return func(r *Runtime) { r.cudaDevice = id }
+}
+
+// WithIntraOpThreads sets the number of intra-op threads, 0 lets ONNX Runtime decide
+func WithIntraOpThreads(n int) Option {
+	return func(r *Runtime) { r.intraOpThreads = n }
+}
+
+// WithDisableCPUArenaOnGPU disables the CPU memory arena in sessions using the cuda
+// execution provider, where it mostly holds host memory that is never reused.
+// It has no effect on CPU sessions.
+func WithDisableCPUArenaOnGPU(disabled bool) Option {
+	return func(r *Runtime) { r.disableCPUArenaOnGPU = disabled }
+}
+
+// NewSessionOptions returns session options configured from the Runtime.
+// The caller must call Destroy on the returned options when no longer needed.
+//
+// When WithDeterministic is enabled the following knobs are set:
+//   - intra-op threads: 1, so kernels don't split work across threads
+//   - inter-op threads: 1, so independent graph nodes run sequentially
+func (r *Runtime) NewSessionOptions() (*ort.SessionOptions, error) {
+	if r.lazyInit {
+		if err := r.Initialize(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	options, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session options: %w", err)
+	}
+
+	if r.intraOpThreads > 0 && !r.deterministic {
+		if err := options.SetIntraOpNumThreads(r.intraOpThreads); err != nil {
+			options.Destroy()
+			return nil, fmt.Errorf("failed to set intra-op threads: %w", err)
+		}
+	}
+
+	if r.deterministic {
+		if err := options.SetIntraOpNumThreads(1); err != nil {
+			options.Destroy()
+			return nil, fmt.Errorf("failed to set intra-op threads: %w", err)
+		}
+		if err := options.SetInterOpNumThreads(1); err != nil {
+			options.Destroy()
+			return nil, fmt.Errorf("failed to set inter-op threads: %w", err)
+		}
+	}
+
+	if r.provider == "cuda" {
+		if err := appendCUDA(options, r.cudaDevice); err != nil {
+			options.Destroy()
+			return nil, err
+		}
+		if r.disableCPUArenaOnGPU {
+			if err := options.SetCpuMemArena(false); err != nil {
+				options.Destroy()
+				return nil, fmt.Errorf("failed to disable CPU memory arena: %w", err)
+			}
+		}
+	}
+	return options, nil
+}
+
+// appendCUDA appends the CUDA execution provider for the given device
+func appendCUDA(options *ort.SessionOptions, device int) error {
+	cudaOptions, err := ort.NewCUDAProviderOptions()
+	if err != nil {
+		return fmt.Errorf("failed to create CUDA provider options: %w", err)
+	}
+	defer cudaOptions.Destroy()
+
+	if err := cudaOptions.Update(map[string]string{"device_id": strconv.Itoa(device)}); err != nil {
+		return fmt.Errorf("failed to set CUDA device: %w", err)
+	}
+	if err := options.AppendExecutionProviderCUDA(cudaOptions); err != nil {
+		return fmt.Errorf("failed to append CUDA provider: %w", err)
+	}
+	return nil
+}
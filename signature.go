@@ -0,0 +1,98 @@
+package onnx
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/joeychilson/onnx/internal/download"
+)
+
+// maxSignatureSize bounds the signature download, which is at most a base64 encoded key
+const maxSignatureSize = 4096
+
+// WithSignatureVerification requires the runtime archive to carry a valid Ed25519ph
+// signature by publicKey, i.e. Ed25519 over the SHA-512 digest of the archive, so large
+// archives are verified without being read into memory. Sign with ed25519.Sign and
+// &ed25519.Options{Hash: crypto.SHA512}. The detached signature is downloaded from the
+// archive URL with ".sig" appended, either raw or base64 encoded, and checked before extraction.
+// Archives failing verification are deleted. Streaming extraction is disabled so
+// nothing is extracted before the signature is checked.
+func WithSignatureVerification(publicKey ed25519.PublicKey) Option {
+	return func(r *Runtime) { r.signingKey = publicKey }
+}
+
+// verifySignature checks archivePath against the detached signature published at the
+// URL it was downloaded from with ".sig" appended. An archive already on disk may have
+// come from any of urls, so it passes if the signature of any of them matches.
+func (r *Runtime) verifySignature(ctx context.Context, urls []string, archivePath string) error {
+	if r.signingKey == nil {
+		return nil
+	}
+
+	digest, err := fileSHA512(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	var errs []error
+	for _, url := range urls {
+		err := r.checkSignature(ctx, url, digest)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// checkSignature checks the SHA-512 digest of an archive against the signature at url+".sig"
+func (r *Runtime) checkSignature(ctx context.Context, url string, digest []byte) error {
+	var sig []byte
+	err := download.Stream(ctx, url+".sig", func(body io.Reader) error {
+		var err error
+		sig, err = io.ReadAll(io.LimitReader(body, maxSignatureSize))
+		return err
+	}, r.runtimeDownloadOptions()...)
+	if err != nil {
+		return fmt.Errorf("%w: failed to download signature: %w", ErrSignatureMismatch, err)
+	}
+
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return fmt.Errorf("%w: malformed signature for %s", ErrSignatureMismatch, url)
+		}
+		sig = decoded
+	}
+
+	if err := ed25519.VerifyWithOptions(r.signingKey, digest, sig, &ed25519.Options{Hash: crypto.SHA512}); err != nil {
+		return fmt.Errorf("%w: %s is not signed by the configured key", ErrSignatureMismatch, url)
+	}
+	return nil
+}
+
+// fileSHA512 streams the file at path through SHA-512
+func fileSHA512(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,304 @@
+package onnx
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidCacheSnapshot is returned by ImportCache when the snapshot is corrupt or
+// contains entries that would be written outside the cache
+var ErrInvalidCacheSnapshot = errors.New("invalid cache snapshot")
+
+// importDirPrefix names the staging directory ImportCache extracts into
+const importDirPrefix = ".import-"
+
+// ExportCache writes the cache directory to w as a tar.gz snapshot, for restoring with
+// ImportCache (e.g. as a single CI cache artifact). Locks and partial downloads are skipped.
+func ExportCache(w io.Writer, opts ...Option) error {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return err
+	}
+
+	// WalkDir doesn't follow a symlinked root, which would export nothing
+	root, err := filepath.EvalSymlinks(r.cachePath)
+	if err != nil {
+		return fmt.Errorf("failed to export cache: %w", err)
+	}
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	err = filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == root {
+			return nil
+		}
+		if isTransientCacheFile(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if d.Type()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		} else if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export cache: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to export cache: %w", err)
+	}
+	if err := gzw.Close(); err != nil {
+		return fmt.Errorf("failed to export cache: %w", err)
+	}
+	return nil
+}
+
+// ImportCache restores a snapshot written by ExportCache into the cache directory. The
+// whole snapshot is extracted and checked before anything in the cache is replaced, so
+// a corrupt or truncated snapshot leaves the cache as it was.
+func ImportCache(rd io.Reader, opts ...Option) error {
+	r, err := newRuntime(opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := r.mkdirCache(r.cachePath); err != nil {
+		return err
+	}
+
+	stageDir, err := os.MkdirTemp(r.cachePath, importDirPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	if err := r.extractSnapshot(rd, stageDir); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCacheSnapshot, err)
+	}
+
+	if err := r.mergeDir(stageDir, r.cachePath); err != nil {
+		return fmt.Errorf("failed to import cache: %w", err)
+	}
+	return nil
+}
+
+// extractSnapshot extracts a cache snapshot into destDir, rejecting entries that escape
+// it by path or through a symlink and verifying the gzip checksum of the whole stream
+func (r *Runtime) extractSnapshot(rd io.Reader, destDir string) error {
+	gzr, err := gzip.NewReader(rd)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if !fs.ValidPath(name) || name == "." || isTransientCacheFile(path.Base(name)) {
+			return fmt.Errorf("unexpected entry %q", header.Name)
+		}
+		destPath := filepath.Join(destDir, filepath.FromSlash(name))
+
+		// Earlier link entries must not redirect later entries outside destDir
+		if err := checkNoSymlinks(destDir, name, header.Typeflag == tar.TypeSymlink); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := r.mkdirCache(destPath); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := r.mkdirCache(filepath.Dir(destPath)); err != nil {
+				return err
+			}
+			if err := r.writeSnapshotFile(tr, destPath, header); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// Only sibling links like libonnxruntime.so -> libonnxruntime.so.1.20.0 are cached
+			if header.Linkname == "" || header.Linkname == "." || header.Linkname == ".." || strings.ContainsAny(header.Linkname, `/\`) {
+				return fmt.Errorf("unexpected link %q -> %q", header.Name, header.Linkname)
+			}
+			if err := r.mkdirCache(filepath.Dir(destPath)); err != nil {
+				return err
+			}
+			os.Remove(destPath)
+			if err := os.Symlink(header.Linkname, destPath); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected entry type %q", header.Name)
+		}
+	}
+
+	// Reading to the end of the gzip stream verifies its checksum
+	_, err = io.Copy(io.Discard, gzr)
+	return err
+}
+
+// checkNoSymlinks returns an error if any component of name under root is a symlink.
+// The last component may be one when allowLast is set, for replacing a link entry.
+func checkNoSymlinks(root, name string, allowLast bool) error {
+	parts := strings.Split(name, "/")
+	current := root
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 && !(allowLast && i == len(parts)-1) {
+			return fmt.Errorf("entry %q is written through a symlink", name)
+		}
+	}
+	return nil
+}
+
+// writeSnapshotFile writes a regular file from a snapshot with the cache file mode
+// and its original modification time, which model cache eviction relies on
+func (r *Runtime) writeSnapshotFile(src io.Reader, destPath string, header *tar.Header) error {
+	f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, r.cacheFileMode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, src)
+	if err != nil {
+		return err
+	}
+	if n != header.Size {
+		return fmt.Errorf("%s: received %d of %d bytes", header.Name, n, header.Size)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(destPath, r.cacheFileMode); err != nil {
+		return err
+	}
+	return os.Chtimes(destPath, header.ModTime, header.ModTime)
+}
+
+// mergeDir moves everything under src into dst, replacing files that already exist
+func (r *Runtime) mergeDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+
+		if entry.IsDir() {
+			if info, err := os.Lstat(dstPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("%s is a symlink, not merging into it", dstPath)
+			}
+			if err := r.mkdirCache(dstPath); err != nil {
+				return err
+			}
+			if err := r.mergeDir(srcPath, dstPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+			os.Remove(dstPath)
+			if err := os.Symlink(target, dstPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := moveFile(srcPath, dstPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTransientCacheFile reports whether name is a lock, partial download or staging
+// directory that only matters to an operation in progress
+func isTransientCacheFile(name string) bool {
+	if strings.HasPrefix(name, importDirPrefix) {
+		return true
+	}
+	for _, suffix := range []string{".lock", ".takeover", ".download", ".extract", ".tmp", ".move"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
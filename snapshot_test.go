@@ -0,0 +1,123 @@
+package onnx_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joeychilson/onnx"
+)
+
+// TestExportSymlinkedCache exports a cache directory reached through a symlink
+func TestExportSymlinkedCache(t *testing.T) {
+	dir := t.TempDir()
+	realPath := filepath.Join(dir, "real")
+	if err := os.MkdirAll(filepath.Join(realPath, "runtime"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(realPath, "runtime", "library"), []byte("library"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	linkPath := filepath.Join(dir, "cache")
+	if err := os.Symlink(realPath, linkPath); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := onnx.ExportCache(&buf, onnx.WithCachePath(linkPath)); err != nil {
+		t.Fatalf("ExportCache: %v", err)
+	}
+
+	importPath := t.TempDir()
+	if err := onnx.ImportCache(&buf, onnx.WithCachePath(importPath)); err != nil {
+		t.Fatalf("ImportCache: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(importPath, "runtime", "library")); err != nil || string(data) != "library" {
+		t.Fatalf("unexpected imported library: %q, %v", data, err)
+	}
+}
+
+// TestImportRejectsLinks rejects snapshots whose links point outside their directory
+// or redirect later entries
+func TestImportRejectsLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tar.Header
+	}{
+		{
+			name:    "parent link",
+			entries: []tar.Header{{Name: "runtime/x", Linkname: "..", Typeflag: tar.TypeSymlink}},
+		},
+		{
+			name: "write through link",
+			entries: []tar.Header{
+				{Name: "runtime/target", Mode: 0755, Typeflag: tar.TypeDir},
+				{Name: "runtime/x", Linkname: "target", Typeflag: tar.TypeSymlink},
+				{Name: "runtime/x/library", Mode: 0644, Typeflag: tar.TypeReg},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, err := cacheSnapshot(tt.entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = onnx.ImportCache(bytes.NewReader(snapshot), onnx.WithCachePath(t.TempDir()))
+			if !errors.Is(err, onnx.ErrInvalidCacheSnapshot) {
+				t.Fatalf("expected ErrInvalidCacheSnapshot, got %v", err)
+			}
+		})
+	}
+}
+
+// TestImportRejectsLinkedCacheDir refuses to merge a snapshot into a cache directory
+// that links outside the cache
+func TestImportRejectsLinkedCacheDir(t *testing.T) {
+	cachePath := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(cachePath, "runtime")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	snapshot, err := cacheSnapshot([]tar.Header{
+		{Name: "runtime/", Mode: 0755, Typeflag: tar.TypeDir},
+		{Name: "runtime/library", Mode: 0644, Typeflag: tar.TypeReg},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := onnx.ImportCache(bytes.NewReader(snapshot), onnx.WithCachePath(cachePath)); err == nil {
+		t.Fatal("expected an error importing through a linked directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "library")); err == nil {
+		t.Fatal("library was written through the linked directory")
+	}
+}
+
+// cacheSnapshot builds a tar.gz snapshot from headers, with empty regular files
+func cacheSnapshot(entries []tar.Header) ([]byte, error) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, header := range entries {
+		if err := tw.WriteHeader(&header); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
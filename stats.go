@@ -0,0 +1,69 @@
+package onnx
+
+import (
+	"fmt"
+
+	"github.com/joeychilson/onnx/internal/model"
+)
+
+// elementBits is the size in bits of each ONNX TensorProto data type
+var elementBits = map[int32]int64{
+	1: 32, 2: 8, 3: 8, 4: 16, 5: 16, 6: 32, 7: 64, 9: 8, 10: 16, 11: 64,
+	12: 32, 13: 64, 14: 64, 15: 128, 16: 16, 17: 8, 18: 8, 19: 8, 20: 8, 21: 4, 22: 4,
+}
+
+// ModelStats is an advisory summary of a model's size and compute cost
+type ModelStats struct {
+	// Parameters is the number of elements across all initializers
+	Parameters int64
+
+	// ParameterBytes is the size of the initializer data, excluding string tensors
+	ParameterBytes int64
+
+	// Nodes is the number of operator nodes, including subgraphs and local functions
+	Nodes int
+
+	// OpCounts is the number of nodes per operator, named as in ValidateModelOps
+	OpCounts map[string]int
+
+	// FLOPs estimates the cost of MatMul, Gemm and Conv nodes with constant weights,
+	// counting one input row of each MatMul and Gemm and one output position of each Conv.
+	// Multiply by the sequence length or output size of the model for a full estimate.
+	FLOPs int64
+}
+
+// GetModelStats parses an ONNX model and returns its parameter count and an estimate of its FLOPs
+func GetModelStats(modelPath string) (*ModelStats, error) {
+	m, err := model.Load(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model: %w", err)
+	}
+
+	stats := &ModelStats{Nodes: len(m.Nodes), OpCounts: make(map[string]int)}
+
+	weights := make(map[string]int64, len(m.Initializers))
+	for _, tensor := range m.Initializers {
+		elements := int64(1)
+		for _, dim := range tensor.Dims {
+			elements *= dim
+		}
+		weights[tensor.Name] = elements
+
+		stats.Parameters += elements
+		stats.ParameterBytes += (elements*elementBits[tensor.DataType] + 7) / 8
+	}
+
+	for _, node := range m.Nodes {
+		stats.OpCounts[opName(node)]++
+
+		if node.Domain != "" || len(node.Inputs) < 2 {
+			continue
+		}
+		switch node.OpType {
+		case "MatMul", "Gemm", "Conv":
+			// Each weight is used in one multiply and one add per input row or output position
+			stats.FLOPs += 2 * weights[node.Inputs[1]]
+		}
+	}
+	return stats, nil
+}
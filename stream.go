@@ -0,0 +1,44 @@
+package onnx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/joeychilson/onnx/internal/archive"
+	"github.com/joeychilson/onnx/internal/download"
+)
+
+// canStream reports whether an archive can be extracted while it downloads
+func canStream(archivePath string) bool {
+	return strings.HasSuffix(archivePath, ".tgz") || strings.HasSuffix(archivePath, ".tar.gz")
+}
+
+// streamExtract downloads a tar.gz runtime archive and extracts it into extractDir on the fly.
+// The rest of the stream is drained so the size covers the whole archive. Archives that must
+// be verified against a checksum manifest or signature are never streamed.
+func (r *Runtime) streamExtract(ctx context.Context, url, extractDir string, info *RuntimeInfo, libPath string) error {
+	// Errors from extraction are returned as-is, anything else is a download failure
+	var resultErr error
+
+	err := download.Stream(ctx, url, func(body io.Reader) error {
+		resultErr = r.extractFiles(url, extractDir, info, libPath,
+			func(destDir string) error {
+				return archive.ExtractDirFromTarGzReader(body, destDir, "lib", r.skipLibrary)
+			},
+			func(destPath, entry string) error { return archive.ExtractFromTarGzReader(body, destPath, entry) },
+		)
+		if resultErr != nil {
+			return resultErr
+		}
+
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}, r.runtimeDownloadOptions()...)
+
+	if err != nil && err != resultErr {
+		return fmt.Errorf("%w: %w", ErrDownload, err)
+	}
+	return err
+}
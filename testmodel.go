@@ -0,0 +1,15 @@
+package onnx
+
+import (
+	"bytes"
+	_ "embed"
+)
+
+//go:embed testdata/identity.onnx
+var testModel []byte
+
+// TestModel returns a minimal ONNX model for smoke tests and examples. It has a
+// single Identity node mapping float32 input "x" of shape [1, 4] to output "y".
+func TestModel() []byte {
+	return bytes.Clone(testModel)
+}
@@ -0,0 +1,9 @@
+package onnx
+
+import "syscall"
+
+// isTranslated reports whether the process is running under Rosetta 2
+func isTranslated() bool {
+	translated, err := syscall.SysctlUint32("sysctl.proc_translated")
+	return err == nil && translated == 1
+}
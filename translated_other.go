@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package onnx
+
+// isTranslated reports whether the process is running under Rosetta 2
+func isTranslated() bool {
+	return false
+}